@@ -1,6 +1,12 @@
 package common
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
 	"time"
 	
 	"github.com/BurntSushi/toml"
@@ -8,38 +14,208 @@ import (
 
 // Configs 全局配置信息
 type Configs struct {
-	Listen  string
-	Monitor *monitorConfig
-	HTTP    *httpConfig
+	Listen   string // 内部接口(/internal、pprof)的监听地址
+	Monitor  *monitorConfig
+	HTTP     *httpConfig
+	GRPC     *grpcConfig
+	Log      *logConfig
+	Profiler *profilerConfig
+	Shutdown *shutdownConfig
+}
+
+type shutdownConfig struct {
+	StartTimeout Duration
+	StopTimeout  Duration
+	DrainDelay   Duration
+}
+
+type profilerConfig struct {
+	Dir                string
+	MaxFiles           int
+	Interval           Duration
+	Profiles           []string
+	CPUDuration        Duration
+	CheckInterval      Duration
+	Cooldown           Duration
+	GoroutineThreshold int
+	HeapGrowthRatio    float64
+	P99Threshold       Duration
+}
+
+type logConfig struct {
+	Level    string
+	Encoding string
+	Output   string
+	Sampling *samplingConfig
+}
+
+type samplingConfig struct {
+	Initial    int
+	Thereafter int
+	Tick       Duration
 }
 
 type monitorConfig struct {
-	Namespace string
-	Subsystem string
+	AllowedCIDRs                []string
+	BearerToken                 string
+	Namespace                   string
+	Subsystem                   string
+	Buckets                     []float64
+	Seconds                     bool
+	SizeBuckets                 []float64
+	NativeHistogramBucketFactor float64
+	NativeHistogramMaxBuckets   uint32
+	LabelLimits                 map[string]labelLimitConfig
+	Sink                        string
+	StatsD                      *statsdConfig
+	TLS                         *TLSConfig
+}
+
+// TLSConfig clientAuth为none、request或require，证书文件更新后按reloadInterval自动重新加载
+type TLSConfig struct {
+	CertFile       string
+	KeyFile        string
+	CAFile         string
+	ClientAuth     string
+	ServerName     string
+	ReloadInterval Duration
+}
+
+type statsdConfig struct {
+	Addr          string
+	Flavor        string
+	FlushInterval Duration
+	MaxPacketSize int
+	MaxSamples    int
+}
+
+type labelLimitConfig struct {
+	Allowlist []string
+	MaxValues int
 }
 
 type httpConfig struct {
-	Listen string
+	Listen         string
+	Timeout        Duration
+	MaxBodyBytes   int64
+	TrustedProxies []string
+	CORS           *corsConfig
+	AccessLog      *accessLogConfig
+	TLS            *TLSConfig
+}
+
+type grpcConfig struct {
+	Listen         string
+	Reflection     bool
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+	Keepalive      *keepaliveConfig
+	AccessLog      *accessLogConfig
+	TLS            *TLSConfig
+}
+
+type keepaliveConfig struct {
+	Time                  Duration
+	Timeout               Duration
+	MaxConnectionIdle     Duration
+	MaxConnectionAge      Duration
+	MaxConnectionAgeGrace Duration
+	MinTime               Duration
+	PermitWithoutStream   bool
+}
+
+type accessLogConfig struct {
+	SuccessSampleRate float64
+	SlowThreshold     Duration
+}
+
+type corsConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int
 }
 
 // Config 全局配置信息
 var Config *Configs
 
+// ConfigHash 配置文件内容的sha256，用于确认各实例加载的配置是否一致
+var ConfigHash string
+
 // InitConfig 加载配置
 func InitConfig(path string) {
-	config, err := loadConfig(path)
+	config, hash, err := loadConfig(path)
 	if err != nil {
 		panic(err)
 	}
 	Config = config
+	ConfigHash = hash
 }
 
-func loadConfig(path string) (*Configs, error) {
+func loadConfig(path string) (*Configs, string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
 	config := new(Configs)
-	if _, err := toml.DecodeFile(path, config); err != nil {
-		return nil, err
+	if _, err := toml.Decode(string(data), config); err != nil {
+		return nil, "", err
+	}
+	if err := validateListen(config); err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return config, hex.EncodeToString(sum[:]), nil
+}
+
+// validateListen 内部接口只依赖监听地址隔离，不能与对外的HTTP服务共用端口
+func validateListen(config *Configs) error {
+	if config.Listen == "" {
+		return errors.New("listen must be set")
+	}
+	if config.HTTP == nil || config.HTTP.Listen == "" {
+		return nil
+	}
+
+	conflict, err := sameListenPort(config.Listen, config.HTTP.Listen)
+	if err != nil {
+		return err
+	}
+	if conflict {
+		return fmt.Errorf("listen %s must differ from http.listen %s", config.Listen, config.HTTP.Listen)
+	}
+	return nil
+}
+
+// sameListenPort 两个地址端口相同，且其中一个监听所有地址或两者的host相同时返回true
+func sameListenPort(a, b string) (bool, error) {
+	hostA, portA, err := net.SplitHostPort(a)
+	if err != nil {
+		return false, err
+	}
+	hostB, portB, err := net.SplitHostPort(b)
+	if err != nil {
+		return false, err
+	}
+	if portA != portB {
+		return false, nil
+	}
+	hostA, hostB = normalizeListenHost(hostA), normalizeListenHost(hostB)
+	return hostA == "" || hostB == "" || hostA == hostB, nil
+}
+
+func normalizeListenHost(host string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.IsUnspecified() {
+			return ""
+		}
+		return ip.String()
 	}
-	return config, nil
+	return host
 }
 
 // Duration 配置中使用的时长
@@ -1 +1,34 @@
 package common
+
+import (
+	"strings"
+
+	"umbrella-go/umbrella-common/errors"
+)
+
+// errorMessages 错误码对应的多语言错误信息，key为小写的语言前缀
+var errorMessages = map[int]map[string]string{
+	errors.InternalErrorCode: {
+		"zh": "服务内部错误",
+		"en": "internal error",
+	},
+}
+
+// ErrorMsg 按调用方的语言返回错误码对应的错误信息，依次匹配languages，都没有时使用英文，未知的错误码返回空字符串
+// 同时用于HTTP的render.ErrorMsgGetter和gRPC的grpcmiddleware.ErrorMsgGetter
+func ErrorMsg(code int, languages []string) string {
+	messages, ok := errorMessages[code]
+	if !ok {
+		return ""
+	}
+	for _, language := range languages {
+		prefix := strings.ToLower(language)
+		if i := strings.IndexAny(prefix, "-_"); i >= 0 {
+			prefix = prefix[:i]
+		}
+		if msg, ok := messages[prefix]; ok {
+			return msg
+		}
+	}
+	return messages["en"]
+}
@@ -1,13 +1,19 @@
 package handler
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi"
 
 	"umbrella-go/handler/v1_0"
+	"umbrella-go/umbrella-common/monitor"
 )
 
-func RegisterBackendRouter() *chi.Mux {
+// middlewares在monitor.HttpMonitor之后执行，可以取到匹配的路由模板
+func RegisterBackendRouter(middlewares ...func(http.Handler) http.Handler) *chi.Mux {
 	router := chi.NewRouter()
+	router.Use(monitor.HttpMonitor)
+	router.Use(middlewares...)
 	registerRouter(router)
 	return router
 }
@@ -1,28 +1,101 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"flag"
-	"fmt"
 	"net/http"
 
+	httpcaller "umbrella-go/umbrella-common/caller/http"
+	"umbrella-go/umbrella-common/lifecycle"
+	"umbrella-go/umbrella-common/log"
+	"umbrella-go/umbrella-common/middleware/grpc"
+	"umbrella-go/umbrella-common/middleware/http"
 	"umbrella-go/umbrella-common/monitor"
+	"umbrella-go/umbrella-common/profiler"
+	"umbrella-go/umbrella-common/server"
+	"umbrella-go/umbrella-common/server/grpc"
+	"umbrella-go/umbrella-common/tlsutil"
 
 	"umbrella-go/common"
 	"umbrella-go/handler"
-	"umbrella-go/utils"
 )
 
 var (
 	BuildTime    = "No Build Time"
 	BuildGitHash = "No Build Git Hash"
 	BuildGitTag  = "No Build Git Tag"
+	// BuildDependencies glide.lock中锁定的依赖版本，格式为path@version,path@version
+	BuildDependencies = ""
 )
 
 func main() {
 	initConfig()
+	initLog()
 	initMonitor()
+	initProfiler()
 
-	startHTTPServer()
+	lc := lifecycle.New(lifecycleConfig())
+	registerHooks(lc)
+	if err := lc.Run(); err != nil {
+		log.Fatal(context.Background(), "exit with error", log.Err(err))
+	}
+}
+
+// registerHooks 按启动顺序添加，退出时按相反的顺序关闭：先排空对外的请求，
+// 再关闭业务依赖的资源，内部接口、profiler和指标最后关闭，保证退出过程仍然可观测
+func registerHooks(lc *lifecycle.Lifecycle) {
+	lc.Append(lifecycle.Closer("monitor", monitor.Monitor.Close))
+	lc.Append(lifecycle.Closer("profiler", func() error {
+		profiler.Stop()
+		return nil
+	}))
+	lc.Append(lc.HTTPServer("monitor server", newMonitorServer(serverTLS(lc, "monitor tls", common.Config.Monitor.TLS))))
+	// 数据库、Redis连接池和订阅客户端在这里通过lifecycle.Closer添加
+
+	httpTLS := serverTLS(lc, "http tls", common.Config.HTTP.TLS)
+	grpcConfig := common.Config.GRPC
+	if grpcConfig == nil {
+		lc.Append(lc.HTTPServer("http server", newHTTPServer(httpTLS)))
+		return
+	}
+
+	if grpcConfig.Listen == "" || grpcConfig.Listen == common.Config.HTTP.Listen {
+		// gRPC与HTTP共用同一个端口，使用HTTP的TLS配置
+		gs := newGRPCServer(nil)
+		mux := server.NewMux(common.Config.HTTP.Listen, newHTTPServer(nil).Handler, gs.Server, httpTLS)
+		lc.Append(lc.Server("http and grpc server", mux.Addr(), mux))
+		appendGRPCHealth(lc, gs)
+		return
+	}
+
+	gs := newGRPCServer(serverTLS(lc, "grpc tls", grpcConfig.TLS))
+	lc.Append(lc.HTTPServer("http server", newHTTPServer(httpTLS)))
+	lc.Append(lc.GRPCServer("grpc server", gs.Server, gs.Addr()))
+	appendGRPCHealth(lc, gs)
+}
+
+func appendGRPCHealth(lc *lifecycle.Lifecycle, gs *grpcserver.Server) {
+	// 先结束health的Watch流，否则关闭gRPC服务时会一直等待这些长连接
+	lc.Append(lifecycle.Hook{
+		Name: "grpc health",
+		OnStop: func(ctx context.Context) error {
+			gs.Health.Shutdown()
+			return nil
+		},
+	})
+}
+
+func lifecycleConfig() lifecycle.Config {
+	shutdown := common.Config.Shutdown
+	if shutdown == nil {
+		return lifecycle.Config{}
+	}
+	return lifecycle.Config{
+		StartTimeout: shutdown.StartTimeout.D(),
+		StopTimeout:  shutdown.StopTimeout.D(),
+		DrainDelay:   shutdown.DrainDelay.D(),
+	}
 }
 
 func initConfig() {
@@ -32,23 +105,206 @@ func initConfig() {
 	common.InitConfig(*configPath)
 }
 
+func initLog() {
+	logConfig := common.Config.Log
+	if logConfig == nil {
+		return
+	}
+
+	level, err := log.ParseLevel(logConfig.Level)
+	if err != nil {
+		panic(err)
+	}
+	config := log.Config{
+		Level:    level,
+		Encoding: logConfig.Encoding,
+		Output:   logConfig.Output,
+	}
+	if sampling := logConfig.Sampling; sampling != nil {
+		config.Sampling = &log.SamplingConfig{
+			Initial:    sampling.Initial,
+			Thereafter: sampling.Thereafter,
+			Tick:       sampling.Tick.D(),
+		}
+	}
+	if err := log.Init(config); err != nil {
+		panic(err)
+	}
+}
+
 func initMonitor() {
-	monitor.Init(common.Config.Monitor.Namespace, common.Config.Monitor.Subsystem)
-	monitor.Monitor.SetVersion(monitor.Version{GitHash: BuildGitHash, GitTag: BuildGitTag, BuildTime: BuildTime})
-	monitor.InitAndListen(common.Config.HTTP.Listen)
+	monitorConfig := common.Config.Monitor
+	config := monitor.Config{
+		Namespace:                   monitorConfig.Namespace,
+		Subsystem:                   monitorConfig.Subsystem,
+		Buckets:                     monitorConfig.Buckets,
+		Seconds:                     monitorConfig.Seconds,
+		SizeBuckets:                 monitorConfig.SizeBuckets,
+		NativeHistogramBucketFactor: monitorConfig.NativeHistogramBucketFactor,
+		NativeHistogramMaxBuckets:   monitorConfig.NativeHistogramMaxBuckets,
+		LabelLimits:                 labelLimits(),
+		Sink:                        monitorConfig.Sink,
+	}
+	if statsd := monitorConfig.StatsD; statsd != nil {
+		config.StatsD = monitor.StatsDConfig{
+			Addr:          statsd.Addr,
+			Flavor:        statsd.Flavor,
+			FlushInterval: statsd.FlushInterval.D(),
+			MaxPacketSize: statsd.MaxPacketSize,
+			MaxSamples:    statsd.MaxSamples,
+		}
+	}
+	monitor.InitWithConfig(config)
+	monitor.Monitor.SetVersion(monitor.Version{GitHash: BuildGitHash, GitTag: BuildGitTag, BuildTime: BuildTime,
+		Dependencies: monitor.ParseDependencies(BuildDependencies), ConfigHash: common.ConfigHash})
 }
 
-func startHTTPServer() {
-	router := handler.RegisterBackendRouter()
-	utils.RegisterPProf(router)
-	monitor.RegisterHandlers(router)
+// newMonitorServer /internal和pprof只在单独的监听地址上提供，不能与对外的HTTP服务共用端口
+func newMonitorServer(tlsConfig *tls.Config) *http.Server {
+	monitorConfig := common.Config.Monitor
+	return monitor.NewServer(monitor.ListenConfig{
+		Listen:       common.Config.Listen,
+		AllowedCIDRs: monitorConfig.AllowedCIDRs,
+		BearerToken:  monitorConfig.BearerToken,
+		TLS:          tlsConfig,
+	})
+}
+
+// serverTLS 未配置时返回nil，使用明文监听；证书文件的定期检查在退出时停止
+func serverTLS(lc *lifecycle.Lifecycle, name string, c *common.TLSConfig) *tls.Config {
+	if c == nil {
+		return nil
+	}
+
+	reloader, err := tlsutil.NewReloader(tlsutil.Config{
+		CertFile:       c.CertFile,
+		KeyFile:        c.KeyFile,
+		CAFile:         c.CAFile,
+		ClientAuth:     c.ClientAuth,
+		ReloadInterval: c.ReloadInterval.D(),
+		OnError: func(err error) {
+			log.Error(context.Background(), "reload certificate failed", log.String("name", name), log.Err(err))
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	config, err := reloader.ServerConfig()
+	if err != nil {
+		panic(err)
+	}
+	lc.Append(lifecycle.Closer(name, reloader.Close))
+	return config
+}
+
+func initProfiler() {
+	profilerConfig := common.Config.Profiler
+	if profilerConfig == nil {
+		return
+	}
+
+	err := profiler.Start(profiler.Config{
+		Dir:                profilerConfig.Dir,
+		MaxFiles:           profilerConfig.MaxFiles,
+		Interval:           profilerConfig.Interval.D(),
+		Profiles:           profilerConfig.Profiles,
+		CPUDuration:        profilerConfig.CPUDuration.D(),
+		CheckInterval:      profilerConfig.CheckInterval.D(),
+		Cooldown:           profilerConfig.Cooldown.D(),
+		GoroutineThreshold: profilerConfig.GoroutineThreshold,
+		HeapGrowthRatio:    profilerConfig.HeapGrowthRatio,
+		P99Threshold:       profilerConfig.P99Threshold.D(),
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func labelLimits() map[string]monitor.LabelLimit {
+	configs := common.Config.Monitor.LabelLimits
+	limits := make(map[string]monitor.LabelLimit, len(configs))
+	for label, c := range configs {
+		limits[label] = monitor.LabelLimit{Allowlist: c.Allowlist, MaxValues: c.MaxValues}
+	}
+	return limits
+}
+
+// newHTTPServer 使用mTLS时，客户端证书的subject覆盖Caller-Name header作为调用方名称
+func newHTTPServer(tlsConfig *tls.Config) *http.Server {
+	router := handler.RegisterBackendRouter(accessLog())
+	callerName := httpmiddleware.ChainServerMiddlewares(
+		httpcaller.ExtractCallerName(),
+		httpcaller.ExtractCallerFromClientCert(),
+	)
+
+	chain, err := httpmiddleware.DefaultServerChain(serverChainConfig())
+	if err != nil {
+		panic(err)
+	}
+
+	return &http.Server{
+		Addr:      common.Config.HTTP.Listen,
+		Handler:   chain.Wrap(callerName.Wrap(router)),
+		TLSConfig: tlsConfig,
+	}
+}
+
+// newGRPCServer 服务通过grpcserver.Register在各自包的init中注册
+func newGRPCServer(tlsConfig *tls.Config) *grpcserver.Server {
+	grpcConfig := common.Config.GRPC
+	config := grpcserver.Config{
+		Listen:         grpcConfig.Listen,
+		Reflection:     grpcConfig.Reflection,
+		MaxRecvMsgSize: grpcConfig.MaxRecvMsgSize,
+		MaxSendMsgSize: grpcConfig.MaxSendMsgSize,
+		ErrorMsgGetter: common.ErrorMsg,
+		AccessLog:      &grpcmiddleware.AccessLogConfig{},
+		TLS:            tlsConfig,
+	}
+	if ka := grpcConfig.Keepalive; ka != nil {
+		config.Keepalive = grpcserver.KeepaliveConfig{
+			Time:                  ka.Time.D(),
+			Timeout:               ka.Timeout.D(),
+			MaxConnectionIdle:     ka.MaxConnectionIdle.D(),
+			MaxConnectionAge:      ka.MaxConnectionAge.D(),
+			MaxConnectionAgeGrace: ka.MaxConnectionAgeGrace.D(),
+			MinTime:               ka.MinTime.D(),
+			PermitWithoutStream:   ka.PermitWithoutStream,
+		}
+	}
+	if accessLog := grpcConfig.AccessLog; accessLog != nil {
+		config.AccessLog.SuccessSampleRate = accessLog.SuccessSampleRate
+		config.AccessLog.SlowThreshold = accessLog.SlowThreshold.D()
+	}
+	return grpcserver.New(config)
+}
+
+func accessLog() func(http.Handler) http.Handler {
+	config := httpmiddleware.AccessLogConfig{}
+	if accessLog := common.Config.HTTP.AccessLog; accessLog != nil {
+		config.SuccessSampleRate = accessLog.SuccessSampleRate
+		config.SlowThreshold = accessLog.SlowThreshold.D()
+	}
+	return httpmiddleware.AccessLog(config).Wrap
+}
 
-	httpServer := &http.Server{
-		Addr:    common.Config.HTTP.Listen,
-		Handler: router,
+func serverChainConfig() httpmiddleware.ServerChainConfig {
+	httpConfig := common.Config.HTTP
+	config := httpmiddleware.ServerChainConfig{
+		TrustedProxies: httpConfig.TrustedProxies,
+		MaxBodyBytes:   httpConfig.MaxBodyBytes,
+		Timeout:        httpConfig.Timeout.D(),
+		ErrorMsgGetter: common.ErrorMsg,
 	}
-	fmt.Printf("start http server, listen: %s!\n", common.Config.HTTP.Listen)
-	if err := httpServer.ListenAndServe(); err != nil {
-		panic("start http server failed")
+	if cors := httpConfig.CORS; cors != nil {
+		config.CORS = &httpmiddleware.CORSConfig{
+			AllowedOrigins:   cors.AllowedOrigins,
+			AllowedMethods:   cors.AllowedMethods,
+			AllowedHeaders:   cors.AllowedHeaders,
+			ExposedHeaders:   cors.ExposedHeaders,
+			AllowCredentials: cors.AllowCredentials,
+			MaxAge:           cors.MaxAge,
+		}
 	}
+	return config
 }
@@ -3,10 +3,13 @@ package grpc
 import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 
 	"umbrella-go/umbrella-common/caller"
 	"umbrella-go/umbrella-common/middleware/grpc"
+	"umbrella-go/umbrella-common/tlsutil"
 )
 
 const (
@@ -70,3 +73,33 @@ func ExtractCallerNameStream() grpc.StreamServerInterceptor {
 		return handler(srv, grpcmiddleware.ServerStreamWithContext(ss, ctx))
 	}
 }
+
+// contextWithCertCallerName 连接使用了经过校验的客户端证书(mTLS)时，使用证书的subject作为调用方名称
+func contextWithCertCallerName(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ctx
+	}
+	if name := tlsutil.SubjectName(&info.State); name != "" {
+		return caller.ContextWithCallerName(ctx, name)
+	}
+	return ctx
+}
+
+// ExtractCallerFromClientCertUnary 证书比caller-name metadata更可信，因此放在ExtractCallerNameUnary之后覆盖metadata中的值
+func ExtractCallerFromClientCertUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		return handler(contextWithCertCallerName(ctx), req)
+	}
+}
+
+func ExtractCallerFromClientCertStream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := contextWithCertCallerName(ss.Context())
+		return handler(srv, grpcmiddleware.ServerStreamWithContext(ss, ctx))
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"umbrella-go/umbrella-common/caller"
 	"umbrella-go/umbrella-common/middleware/http"
+	"umbrella-go/umbrella-common/tlsutil"
 )
 
 const (
@@ -35,3 +36,14 @@ func ExtractCallerName() httpmiddleware.ServerMiddleware {
 		next.ServeHTTP(rw, req.WithContext(caller.ContextWithCallerName(req.Context(), name)))
 	}
 }
+
+// ExtractCallerFromClientCert 使用经过校验的客户端证书(mTLS)的subject作为调用方名称，
+// 证书比Caller-Name header更可信，因此放在ExtractCallerName之后覆盖header中的值
+func ExtractCallerFromClientCert() httpmiddleware.ServerMiddleware {
+	return func(rw http.ResponseWriter, req *http.Request, next http.Handler) {
+		if name := tlsutil.SubjectName(req.TLS); name != "" {
+			req = req.WithContext(caller.ContextWithCallerName(req.Context(), name))
+		}
+		next.ServeHTTP(rw, req)
+	}
+}
@@ -0,0 +1,160 @@
+package sql
+
+import (
+	"container/list"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	fingerprintStringRe  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.)*"`)
+	fingerprintNumberRe  = regexp.MustCompile(`\b-?\d+(?:\.\d+)?\b`)
+	fingerprintInListRe  = regexp.MustCompile(`(?i)\bin\s*\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	fingerprintValuesRe  = regexp.MustCompile(`(?i)\bvalues\s*\(`)
+	fingerprintSpaceRe   = regexp.MustCompile(`\s+`)
+	fingerprintCommentRe = regexp.MustCompile(`(?s)/\*.*?\*/|--[^\n]*`)
+
+	tableRe = regexp.MustCompile("(?i)\\b(?:from|into|update|join|table)\\s+`?([\\w.]+)`?")
+)
+
+const (
+	fingerprintCacheSize     = 1024
+	fingerprintMaxCachedSize = 4096 // 超过该长度的语句不缓存，避免缓存占用过多内存
+)
+
+// fingerprintCache 有界的LRU缓存，同一语句每次执行都要计算fingerprint，正则替换的开销较大
+type fingerprintCache struct {
+	sync.Mutex
+	size    int
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+type fingerprintEntry struct {
+	query       string
+	fingerprint string
+}
+
+func newFingerprintCache(size int) *fingerprintCache {
+	return &fingerprintCache{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+func (c *fingerprintCache) get(query string) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+	e, ok := c.entries[query]
+	if !ok {
+		return "", false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*fingerprintEntry).fingerprint, true
+}
+
+func (c *fingerprintCache) add(query, fingerprint string) {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[query]; ok {
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.entries[query] = c.ll.PushFront(&fingerprintEntry{query: query, fingerprint: fingerprint})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.entries, oldest.Value.(*fingerprintEntry).query)
+	}
+}
+
+var fingerprints = newFingerprintCache(fingerprintCacheSize)
+
+// Fingerprint 将SQL语句归一化：去掉注释，字面量替换为?，IN列表和VALUES合并，压缩空白并转为小写
+// 同一模板的语句得到相同的结果，可以用作监控标签和日志聚合，结果缓存在有界的LRU中
+func Fingerprint(query string) string {
+	if len(query) > fingerprintMaxCachedSize {
+		return fingerprint(query)
+	}
+	if fp, ok := fingerprints.get(query); ok {
+		return fp
+	}
+	fp := fingerprint(query)
+	fingerprints.add(query, fp)
+	return fp
+}
+
+func fingerprint(query string) string {
+	q := fingerprintCommentRe.ReplaceAllString(query, " ")
+	q = fingerprintStringRe.ReplaceAllString(q, "?")
+	q = fingerprintNumberRe.ReplaceAllString(q, "?")
+	q = fingerprintInListRe.ReplaceAllString(q, "in (?)")
+	q = replaceValues(q)
+	q = fingerprintSpaceRe.ReplaceAllString(q, " ")
+	return strings.ToLower(strings.TrimSpace(q))
+}
+
+// closingParen 返回q[open]处的左括号对应的右括号位置，字符串字面量已替换为?，不需要处理引号中的括号
+func closingParen(q string, open int) int {
+	depth := 0
+	for i := open; i < len(q); i++ {
+		switch q[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// replaceValues 将VALUES之后的一个或多个括号组合并为values (?)，括号组中可以嵌套函数调用等括号，
+// 正则无法匹配嵌套的括号，因此逐个找到配对的右括号
+func replaceValues(q string) string {
+	var b strings.Builder
+	for {
+		loc := fingerprintValuesRe.FindStringIndex(q)
+		if loc == nil {
+			b.WriteString(q)
+			return b.String()
+		}
+		end := closingParen(q, loc[1]-1)
+		if end < 0 {
+			b.WriteString(q)
+			return b.String()
+		}
+		// 后续以逗号分隔的括号组
+		for {
+			rest := strings.TrimLeft(q[end+1:], " \t\r\n")
+			if !strings.HasPrefix(rest, ",") {
+				break
+			}
+			next := strings.TrimLeft(rest[1:], " \t\r\n")
+			if !strings.HasPrefix(next, "(") {
+				break
+			}
+			nextEnd := closingParen(q, len(q)-len(next))
+			if nextEnd < 0 {
+				break
+			}
+			end = nextEnd
+		}
+		b.WriteString(q[:loc[0]])
+		b.WriteString("values (?)")
+		q = q[end+1:]
+	}
+}
+
+// TableName 返回语句中第一个出现的表名，取不到时返回空字符串
+func TableName(query string) string {
+	m := tableRe.FindStringSubmatch(fingerprintCommentRe.ReplaceAllString(query, " "))
+	if len(m) < 2 {
+		return ""
+	}
+	return strings.ToLower(m[1])
+}
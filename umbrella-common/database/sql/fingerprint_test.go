@@ -0,0 +1,66 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("select * from users where id = ? and name = ?",
+		Fingerprint("SELECT *  FROM users\n WHERE id = 42 AND name = 'o''brien'"))
+	assert.Equal("select id from orders where status in (?)",
+		Fingerprint("select id from orders where status in (1, 2, 3)"))
+	assert.Equal("insert into logs (a, b) values (?)",
+		Fingerprint("INSERT INTO logs (a, b) VALUES (1, 'x'), (2, 'y')"))
+	// VALUES中嵌套括号
+	assert.Equal("insert into t (a, b) values (?)",
+		Fingerprint("INSERT INTO t (a, b) VALUES (f(1), 2)"))
+	assert.Equal("insert into t (a, b) values (?) on duplicate key update b = ?",
+		Fingerprint("INSERT INTO t (a, b) VALUES (now(), ')'), (coalesce(null, (3)), 4) ON DUPLICATE KEY UPDATE b = 5"))
+	assert.Equal("insert into t values (?", Fingerprint("INSERT INTO t VALUES (1"))
+}
+
+func TestFingerprintCache(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newFingerprintCache(2)
+	c.add("a", "fa")
+	c.add("b", "fb")
+	_, ok := c.get("a")
+	assert.True(ok)
+
+	// b最久未使用，被淘汰
+	c.add("c", "fc")
+	_, ok = c.get("b")
+	assert.False(ok)
+	fp, ok := c.get("a")
+	assert.True(ok)
+	assert.Equal("fa", fp)
+	assert.Equal(2, c.ll.Len())
+
+	assert.Equal(Fingerprint("select 1"), Fingerprint("select 1"))
+}
+
+func TestTableName(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("users", TableName("SELECT * FROM `users` WHERE id = 1"))
+	assert.Equal("logs", TableName("insert into logs values (1)"))
+	assert.Equal("db.orders", TableName("UPDATE db.orders SET status = 1"))
+	assert.Equal("", TableName("BEGIN"))
+}
+
+func TestRedactors(t *testing.T) {
+	assert := assert.New(t)
+
+	args := []interface{}{42, "secret", []byte("token"), true}
+	assert.Equal([]interface{}{"?", "?", "?", "?"}, RedactAll(args))
+	assert.Equal([]interface{}{42, "<string len=6>", "<bytes len=5>", true}, RedactStrings(args))
+	assert.Equal(args, RedactNone(args))
+
+	assert.True(isSelect("  (SELECT 1) UNION (SELECT 2)"))
+	assert.False(isSelect("update users set name = ?"))
+}
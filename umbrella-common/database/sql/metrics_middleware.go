@@ -0,0 +1,171 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+const (
+	OpExec     = "exec"
+	OpQuery    = "query"
+	OpBegin    = "begin"
+	OpCommit   = "commit"
+	OpRollback = "rollback"
+)
+
+var sqlMetricLabels = []string{"db", "operation", "table", "statement"}
+
+// sqlMetrics 所有MetricsMiddleware共用同一组指标，以db标签区分
+type sqlMetrics struct {
+	registry prometheus.Registerer // 注册指标的monitor的registry
+	latency  *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+var (
+	sqlMetricsCurrent atomic.Value // *sqlMetrics
+	sqlMetricsLock    sync.Mutex
+	sqlMetricsLoaded  = make(map[prometheus.Registerer]*sqlMetrics)
+)
+
+// loadSQLMetrics 返回注册在当前monitor.Monitor上的指标，第一次调用时注册，
+// monitor.Monitor被替换(如测试中)后重新注册到新的monitor上，monitor未初始化时返回nil，不记录指标
+func loadSQLMetrics() *sqlMetrics {
+	m := monitor.Monitor
+	if m == nil {
+		return nil
+	}
+	registry := m.Registerer()
+	if metrics, _ := sqlMetricsCurrent.Load().(*sqlMetrics); metrics != nil && metrics.registry == registry {
+		return metrics
+	}
+
+	sqlMetricsLock.Lock()
+	defer sqlMetricsLock.Unlock()
+	metrics, ok := sqlMetricsLoaded[registry]
+	if !ok {
+		metrics = &sqlMetrics{registry: registry}
+		metrics.latency, _ = m.NewHistogram(prometheus.HistogramOpts{
+			Name: monitor.DurationName("sql_duration"),
+			Help: "sql operation latency",
+		}, sqlMetricLabels...)
+		metrics.errors, _ = m.NewCounter(prometheus.CounterOpts{
+			Name: "sql_error_count",
+			Help: "sql operation error counter",
+		}, sqlMetricLabels...)
+		sqlMetricsLoaded[registry] = metrics
+	}
+	sqlMetricsCurrent.Store(metrics)
+	return metrics
+}
+
+type statementKey struct{}
+
+type statement struct {
+	table       string
+	fingerprint string
+}
+
+func statementFromContext(mctx MiddlewareContext) statement {
+	s, _ := mctx.Value(statementKey{}).(statement)
+	return s
+}
+
+// MetricsMiddleware 按操作(exec/query/begin/commit/rollback)、表名和归一化后的语句记录耗时和错误
+type MetricsMiddleware struct {
+	DefaultDBMiddleware
+	name string
+}
+
+// NewMetricsMiddleware name为数据库的名称，作为db标签，可以在monitor.Init之前创建
+func NewMetricsMiddleware(name string) *MetricsMiddleware {
+	return &MetricsMiddleware{name: name}
+}
+
+func (mm *MetricsMiddleware) observe(op string, stmt statement, start time.Time, err error) {
+	metrics := loadSQLMetrics()
+	if metrics == nil {
+		return
+	}
+	values := []string{mm.name, op, stmt.table, stmt.fingerprint}
+	if metrics.latency != nil {
+		metrics.latency.WithLabelValues(values...).Observe(monitor.DurationValue(time.Now().Sub(start)))
+	}
+	if err != nil && err != sql.ErrNoRows && metrics.errors != nil {
+		metrics.errors.WithLabelValues(values...).Inc()
+	}
+}
+
+// newStatement statement标签经过monitor的标签限制，超出限制的语句记为monitor.OverflowLabelValue
+func newStatement(query string) statement {
+	return statement{
+		table:       TableName(query),
+		fingerprint: monitor.LimitLabel("statement", Fingerprint(query)),
+	}
+}
+
+func (mm *MetricsMiddleware) ExecContext(mctx MiddlewareContext, ctx context.Context, next ExecContextFunc, query string, args []interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := next(mctx, ctx, query, args)
+	mm.observe(OpExec, newStatement(query), start, err)
+	return result, err
+}
+
+func (mm *MetricsMiddleware) QueryContext(mctx MiddlewareContext, ctx context.Context, next QueryContextFunc, query string, args []interface{}) (*sql.Rows, MiddlewareContext, error) {
+	start := time.Now()
+	rows, mctx, err := next(mctx, ctx, query, args)
+	mm.observe(OpQuery, newStatement(query), start, err)
+	return rows, mctx, err
+}
+
+// QueryRowContext 的错误要到Scan时才能拿到，语句信息保存在mctx中由ScanRow记录错误
+func (mm *MetricsMiddleware) QueryRowContext(mctx MiddlewareContext, ctx context.Context, next QueryRowContextFunc, query string, args []interface{}) (*sql.Row, MiddlewareContext) {
+	stmt := newStatement(query)
+	start := time.Now()
+	row, mctx := next(mctx, ctx, query, args)
+	mm.observe(OpQuery, stmt, start, nil)
+	return row, context.WithValue(mctx, statementKey{}, stmt)
+}
+
+func (mm *MetricsMiddleware) ScanRow(mctx MiddlewareContext, next ScanFunc, dest []interface{}) error {
+	err := next(mctx, dest)
+	if err == nil || err == sql.ErrNoRows {
+		return err
+	}
+	if metrics := loadSQLMetrics(); metrics != nil && metrics.errors != nil {
+		stmt := statementFromContext(mctx)
+		metrics.errors.WithLabelValues(mm.name, OpQuery, stmt.table, stmt.fingerprint).Inc()
+	}
+	return err
+}
+
+func (mm *MetricsMiddleware) BeginTx(mctx MiddlewareContext, ctx context.Context, opts *sql.TxOptions, next BeginTxFunc) (*sql.Tx, MiddlewareContext, error) {
+	start := time.Now()
+	tx, mctx, err := next(mctx, ctx, opts)
+	mm.observe(OpBegin, statement{}, start, err)
+	return tx, mctx, err
+}
+
+func (mm *MetricsMiddleware) Commit(mctx MiddlewareContext, next CommitFunc) error {
+	start := time.Now()
+	err := next(mctx)
+	mm.observe(OpCommit, statement{}, start, err)
+	return err
+}
+
+func (mm *MetricsMiddleware) Rollback(mctx MiddlewareContext, next RollbackFunc) error {
+	start := time.Now()
+	err := next(mctx)
+	if err == sql.ErrTxDone {
+		err = nil
+	}
+	mm.observe(OpRollback, statement{}, start, err)
+	return err
+}
@@ -0,0 +1,69 @@
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+func sqlSampleCount(t *testing.T, values ...string) uint64 {
+	var m dto.Metric
+	if err := loadSQLMetrics().latency.WithLabelValues(values...).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func sqlErrorCount(values ...string) float64 {
+	return testutil.ToFloat64(loadSQLMetrics().errors.WithLabelValues(values...))
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	assert := assert.New(t)
+
+	old := monitor.Monitor
+	monitor.Monitor = monitor.New(monitor.Config{Namespace: "test", Subsystem: "sql"})
+	defer func() { monitor.Monitor = old }()
+
+	db, err := Open("slowquery-fast", "", NewMetricsMiddleware("main"))
+	assert.Nil(err)
+	defer db.Close()
+	ctx := context.Background()
+
+	update := []string{"main", OpExec, "users", "update users set name = ? where id = ?"}
+	for _, query := range []string{"UPDATE users SET name = ? WHERE id = 1", "UPDATE users SET name = ? WHERE id = 2"} {
+		_, err = db.ExecContext(ctx, query, "x")
+		assert.Nil(err)
+	}
+	assert.Equal(uint64(2), sqlSampleCount(t, update...))
+	assert.Equal(float64(0), sqlErrorCount(update...))
+
+	// 失败的语句同时记录耗时和错误
+	deleteMissing := []string{"main", OpExec, "missing", "delete from missing where id = ?"}
+	_, err = db.ExecContext(ctx, "DELETE FROM missing WHERE id = 1")
+	assert.Equal(errMissingTable, err)
+	assert.Equal(uint64(1), sqlSampleCount(t, deleteMissing...))
+	assert.Equal(float64(1), sqlErrorCount(deleteMissing...))
+
+	// QueryRow的错误在Scan时记录
+	selectMissing := []string{"main", OpQuery, "missing", "select id from missing where id = ?"}
+	var id int64
+	assert.Equal(errMissingTable, db.QueryRowContext(ctx, "SELECT id FROM missing WHERE id = 1").Scan(&id))
+	assert.Equal(uint64(1), sqlSampleCount(t, selectMissing...))
+	assert.Equal(float64(1), sqlErrorCount(selectMissing...))
+
+	// monitor.Monitor被替换后指标注册到新的monitor上
+	first := loadSQLMetrics()
+	monitor.Monitor = monitor.New(monitor.Config{Namespace: "test", Subsystem: "sql"})
+	_, err = db.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = 3", "x")
+	assert.Nil(err)
+	assert.NotEqual(first, loadSQLMetrics())
+	assert.Equal(uint64(1), sqlSampleCount(t, update...))
+	assert.Equal(float64(0), sqlErrorCount(deleteMissing...))
+}
@@ -0,0 +1,236 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"umbrella-go/umbrella-common/log"
+)
+
+// ArgRedactor 在记录慢查询之前处理参数，避免敏感数据写入日志
+type ArgRedactor func(args []interface{}) []interface{}
+
+// RedactNone 原样记录参数
+func RedactNone(args []interface{}) []interface{} {
+	return args
+}
+
+// RedactAll 只记录参数个数，每个参数替换为"?"
+func RedactAll(args []interface{}) []interface{} {
+	result := make([]interface{}, len(args))
+	for i := range args {
+		result[i] = "?"
+	}
+	return result
+}
+
+// RedactStrings 保留数字、布尔、时间等类型的参数，字符串和[]byte只记录长度
+func RedactStrings(args []interface{}) []interface{} {
+	result := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			result[i] = fmt.Sprintf("<string len=%d>", len(v))
+		case []byte:
+			result[i] = fmt.Sprintf("<bytes len=%d>", len(v))
+		case sql.NullString:
+			result[i] = fmt.Sprintf("<string len=%d>", len(v.String))
+		default:
+			result[i] = arg
+		}
+	}
+	return result
+}
+
+type SlowQueryConfig struct {
+	Name      string        // 数据库的名称
+	Threshold time.Duration // 耗时超过该值的语句记录为慢查询
+	Redactor  ArgRedactor   // 为空时使用RedactAll
+	Logger    *log.Logger   // 为空时使用log.Named("sql.slow")
+
+	// ExplainDB 不为空时对慢的SELECT语句执行EXPLAIN并记录结果，
+	// 需要传入未经过中间件包装的*sql.DB，避免EXPLAIN本身再次经过中间件
+	ExplainDB      *sql.DB
+	ExplainTimeout time.Duration // 为0时使用1秒
+	MaxExplains    int           // 同时执行的EXPLAIN数，超过时只记录慢查询本身，为0时使用1
+}
+
+type slowQueryKey struct{}
+
+// slowQuery 保存在mctx中，用于在CloseRows/ScanRow时计算从QueryContext开始的总耗时
+type slowQuery struct {
+	ctx   context.Context
+	op    string
+	query string
+	args  []interface{}
+	start time.Time
+	done  int32
+}
+
+// SlowQueryMiddleware 记录耗时超过阈值的语句，查询的耗时从QueryContext开始，
+// 到CloseRows(QueryRow为Scan)为止，包含读取所有结果行的时间
+type SlowQueryMiddleware struct {
+	DefaultDBMiddleware
+	config   SlowQueryConfig
+	explains chan struct{}
+}
+
+func NewSlowQueryMiddleware(config SlowQueryConfig) *SlowQueryMiddleware {
+	if config.Redactor == nil {
+		config.Redactor = RedactAll
+	}
+	if config.Logger == nil {
+		config.Logger = log.Named("sql.slow")
+	}
+	if config.ExplainTimeout <= 0 {
+		config.ExplainTimeout = time.Second
+	}
+	if config.MaxExplains <= 0 {
+		config.MaxExplains = 1
+	}
+
+	return &SlowQueryMiddleware{
+		config:   config,
+		explains: make(chan struct{}, config.MaxExplains),
+	}
+}
+
+func (sm *SlowQueryMiddleware) finish(sq *slowQuery, err error) {
+	if !atomic.CompareAndSwapInt32(&sq.done, 0, 1) {
+		return
+	}
+
+	cost := time.Now().Sub(sq.start)
+	if cost < sm.config.Threshold {
+		return
+	}
+
+	fields := []log.Field{
+		log.String("db", sm.config.Name),
+		log.String("operation", sq.op),
+		log.String("table", TableName(sq.query)),
+		log.String("fingerprint", Fingerprint(sq.query)),
+		log.String("query", sq.query),
+		log.Any("args", sm.config.Redactor(sq.args)),
+		log.Duration("latency", cost),
+	}
+	if err != nil && err != sql.ErrNoRows {
+		fields = append(fields, log.Err(err))
+	}
+
+	if sm.config.ExplainDB == nil || !isSelect(sq.query) {
+		sm.config.Logger.Warn(sq.ctx, "slow query", fields...)
+		return
+	}
+
+	select {
+	case sm.explains <- struct{}{}:
+	default:
+		sm.config.Logger.Warn(sq.ctx, "slow query", append(fields, log.String("explain", "skipped"))...)
+		return
+	}
+	// EXPLAIN在单独的连接上异步执行，不增加业务请求的耗时
+	go func() {
+		defer func() { <-sm.explains }()
+
+		plan, err := sm.explain(sq.query, sq.args)
+		if err != nil {
+			fields = append(fields, log.String("explain_error", err.Error()))
+		} else {
+			fields = append(fields, log.Any("explain", plan))
+		}
+		sm.config.Logger.Warn(sq.ctx, "slow query", fields...)
+	}()
+}
+
+func isSelect(query string) bool {
+	query = strings.TrimLeft(query, " \t\r\n(")
+	return len(query) >= 6 && strings.EqualFold(query[:6], "select")
+}
+
+func (sm *SlowQueryMiddleware) explain(query string, args []interface{}) ([]map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sm.config.ExplainTimeout)
+	defer cancel()
+
+	conn, err := sm.config.ExplainDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "EXPLAIN "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []map[string]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			if values[i].Valid {
+				row[column] = values[i].String
+			}
+		}
+		plan = append(plan, row)
+	}
+	return plan, rows.Err()
+}
+
+func (sm *SlowQueryMiddleware) ExecContext(mctx MiddlewareContext, ctx context.Context, next ExecContextFunc, query string, args []interface{}) (sql.Result, error) {
+	sq := &slowQuery{ctx: ctx, op: OpExec, query: query, args: args, start: time.Now()}
+	result, err := next(mctx, ctx, query, args)
+	sm.finish(sq, err)
+	return result, err
+}
+
+func (sm *SlowQueryMiddleware) QueryContext(mctx MiddlewareContext, ctx context.Context, next QueryContextFunc, query string, args []interface{}) (*sql.Rows, MiddlewareContext, error) {
+	sq := &slowQuery{ctx: ctx, op: OpQuery, query: query, args: args, start: time.Now()}
+	rows, mctx, err := next(mctx, ctx, query, args)
+	if err != nil {
+		sm.finish(sq, err)
+		return rows, mctx, err
+	}
+	return rows, context.WithValue(mctx, slowQueryKey{}, sq), nil
+}
+
+func (sm *SlowQueryMiddleware) CloseRows(mctx MiddlewareContext, next CloseFunc) error {
+	err := next(mctx)
+	if sq, ok := mctx.Value(slowQueryKey{}).(*slowQuery); ok {
+		sm.finish(sq, err)
+	}
+	return err
+}
+
+// QueryRowContext 的结果要到Scan时才读取，在ScanRow中结束计时
+func (sm *SlowQueryMiddleware) QueryRowContext(mctx MiddlewareContext, ctx context.Context, next QueryRowContextFunc, query string, args []interface{}) (*sql.Row, MiddlewareContext) {
+	sq := &slowQuery{ctx: ctx, op: OpQuery, query: query, args: args, start: time.Now()}
+	row, mctx := next(mctx, ctx, query, args)
+	return row, context.WithValue(mctx, slowQueryKey{}, sq)
+}
+
+func (sm *SlowQueryMiddleware) ScanRow(mctx MiddlewareContext, next ScanFunc, dest []interface{}) error {
+	err := next(mctx, dest)
+	if sq, ok := mctx.Value(slowQueryKey{}).(*slowQuery); ok {
+		sm.finish(sq, err)
+	}
+	return err
+}
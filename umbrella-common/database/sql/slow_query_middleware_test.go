@@ -0,0 +1,205 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"umbrella-go/umbrella-common/json"
+	"umbrella-go/umbrella-common/log"
+)
+
+// fakeDriver 每行结果在Next时等待rowDelay，模拟读取结果集的耗时；EXPLAIN语句返回固定的执行计划，
+// 涉及missing表的语句返回errMissingTable
+type fakeDriver struct {
+	rowDelay time.Duration
+}
+
+func (d fakeDriver) Open(name string) (driver.Conn, error) {
+	return fakeConn{rowDelay: d.rowDelay}, nil
+}
+
+type fakeConn struct {
+	rowDelay time.Duration
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{conn: c, query: query}, nil
+}
+
+func (c fakeConn) Close() error { return nil }
+
+func (c fakeConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type fakeStmt struct {
+	conn  fakeConn
+	query string
+}
+
+func (s fakeStmt) Close() error { return nil }
+
+func (s fakeStmt) NumInput() int { return -1 }
+
+var errMissingTable = errors.New("table missing doesn't exist")
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "missing") {
+		return nil, errMissingTable
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "missing") {
+		return nil, errMissingTable
+	}
+	if strings.HasPrefix(s.query, "EXPLAIN ") {
+		return &fakeRows{columns: []string{"table", "type"}, values: [][]driver.Value{{"users", "ALL"}}}, nil
+	}
+	return &fakeRows{
+		columns: []string{"id"},
+		values:  [][]driver.Value{{int64(1)}, {int64(2)}},
+		delay:   s.conn.rowDelay,
+	}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	delay   time.Duration
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	time.Sleep(r.delay)
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("slowquery-fast", fakeDriver{})
+	sql.Register("slowquery-slow", fakeDriver{rowDelay: 30 * time.Millisecond})
+}
+
+func newTestSlowQueryLogger(t *testing.T) (*log.Logger, string) {
+	f, err := ioutil.TempFile("", "slowquery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	logger, err := log.New(log.Config{Output: f.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger, f.Name()
+}
+
+func readSlowQueryLogs(t *testing.T, name string) []map[string]interface{} {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSlowQueryIncludesReadingRows(t *testing.T) {
+	assert := assert.New(t)
+
+	logger, name := newTestSlowQueryLogger(t)
+	defer os.Remove(name)
+	sm := NewSlowQueryMiddleware(SlowQueryConfig{Name: "test", Threshold: 20 * time.Millisecond, Logger: logger})
+
+	fast, err := Open("slowquery-fast", "", sm)
+	assert.Nil(err)
+	defer fast.Close()
+	rows, err := fast.QueryContext(context.Background(), "SELECT id FROM users WHERE name = ?", "secret")
+	assert.Nil(err)
+	for rows.Next() {
+	}
+	rows.Close()
+	assert.Equal(0, len(readSlowQueryLogs(t, name)))
+
+	// QueryContext本身立即返回，读取结果行的耗时超过阈值
+	slow, err := Open("slowquery-slow", "", sm)
+	assert.Nil(err)
+	defer slow.Close()
+	rows, err = slow.QueryContext(context.Background(), "SELECT id FROM users WHERE name = ?", "secret")
+	assert.Nil(err)
+	for rows.Next() {
+	}
+	rows.Close()
+
+	var id int64
+	assert.Nil(slow.QueryRowContext(context.Background(), "SELECT id FROM users LIMIT 1").Scan(&id))
+	assert.Equal(int64(1), id)
+
+	entries := readSlowQueryLogs(t, name)
+	if assert.Equal(2, len(entries)) {
+		assert.Equal("slow query", entries[0]["msg"])
+		assert.Equal("users", entries[0]["table"])
+		assert.Equal([]interface{}{"?"}, entries[0]["args"])
+		latency, err := time.ParseDuration(entries[0]["latency"].(string))
+		assert.Nil(err)
+		assert.True(latency >= 60*time.Millisecond)
+		assert.Equal("select id from users limit ?", entries[1]["fingerprint"])
+	}
+}
+
+func TestSlowQueryExplain(t *testing.T) {
+	assert := assert.New(t)
+
+	logger, name := newTestSlowQueryLogger(t)
+	defer os.Remove(name)
+	explainDB, err := sql.Open("slowquery-fast", "")
+	assert.Nil(err)
+	defer explainDB.Close()
+	sm := NewSlowQueryMiddleware(SlowQueryConfig{Name: "test", Threshold: 20 * time.Millisecond, Logger: logger, ExplainDB: explainDB})
+
+	slow, err := Open("slowquery-slow", "", sm)
+	assert.Nil(err)
+	defer slow.Close()
+	rows, err := slow.QueryContext(context.Background(), "SELECT id FROM users")
+	assert.Nil(err)
+	for rows.Next() {
+	}
+	rows.Close()
+
+	// EXPLAIN异步执行，记录日志之后才释放explains中的位置
+	sm.explains <- struct{}{}
+	<-sm.explains
+
+	entries := readSlowQueryLogs(t, name)
+	if assert.Equal(1, len(entries)) {
+		assert.Equal([]interface{}{map[string]interface{}{"table": "users", "type": "ALL"}}, entries[0]["explain"])
+	}
+}
@@ -0,0 +1,68 @@
+package sql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+// DBStatsCollector 将sql.DBStats导出为prometheus指标，每个数据库一个实例
+type DBStatsCollector struct {
+	db *sql.DB
+
+	maxOpen      *prometheus.Desc
+	open         *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+}
+
+func NewDBStatsCollector(namespace, subsystem, name string, db *sql.DB) *DBStatsCollector {
+	labels := prometheus.Labels{"db": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, metric), help, nil, labels)
+	}
+
+	return &DBStatsCollector{
+		db:           db,
+		maxOpen:      desc("db_max_open_connections", "maximum number of open connections to the database"),
+		open:         desc("db_open_connections", "number of established connections both in use and idle"),
+		inUse:        desc("db_in_use_connections", "number of connections currently in use"),
+		idle:         desc("db_idle_connections", "number of idle connections"),
+		waitCount:    desc("db_wait_count_total", "total number of connections waited for"),
+		waitDuration: desc("db_wait_duration_seconds_total", "total time blocked waiting for a new connection"),
+	}
+}
+
+func (c *DBStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+}
+
+func (c *DBStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+}
+
+// RegisterDBStats 在monitor中注册db的连接池指标，name作为db标签
+func RegisterDBStats(name string, db *DB) error {
+	if monitor.Monitor == nil {
+		return errors.New("monitor is not initialized")
+	}
+
+	m := monitor.Monitor
+	return m.Registerer().Register(NewDBStatsCollector(m.Namespace(), m.Subsystem(), name, UnwrapDB(db)))
+}
@@ -20,7 +20,7 @@ func PProfHandlers(prefix string) map[string]http.Handler {
 	m[prefix+"/profile"] = http.HandlerFunc(pprof.Profile)
 	m[prefix+"/symbol"] = http.HandlerFunc(pprof.Symbol)
 	m[prefix+"/cmdline"] = http.HandlerFunc(pprof.Cmdline)
-	m[prefix+"/trace "] = http.HandlerFunc(pprof.Trace)
+	m[prefix+"/trace"] = http.HandlerFunc(pprof.Trace)
 	m[prefix+"/heap"] = pprof.Handler("heap")
 	m[prefix+"/goroutine"] = pprof.Handler("goroutine")
 	m[prefix+"/threadcreate"] = pprof.Handler("threadcreate")
@@ -6,6 +6,9 @@ import (
 	proto "umbrella-go/umbrella-common/proto"
 )
 
+// InternalErrorCode 服务内部错误(如panic)使用的错误码
+const InternalErrorCode = 500
+
 type Error interface {
 	GetCode() int
 	GetMessage() string
@@ -22,6 +25,10 @@ func NewError(code int, description string) Error {
 	}
 }
 
+func NewInternalError(description string) Error {
+	return NewError(InternalErrorCode, description)
+}
+
 type UmbrellaError struct {
 	Code        int    `json:"code"`
 	Message     string `json:"message"`               // 用于显示前端错误提示
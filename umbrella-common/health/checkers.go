@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"umbrella-go/umbrella-common/redis/pool"
+)
+
+// Pinger database/sql的*sql.DB以及umbrella-common/database/sql的*DB都满足该接口
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+func SQLChecker(db Pinger) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	})
+}
+
+// RedisChecker 从连接池取一个连接执行PING
+func RedisChecker(p *pool.Pool) Checker {
+	return CheckerFunc(func(ctx context.Context) (err error) {
+		conn, err := p.Get()
+		if err != nil {
+			return err
+		}
+		defer p.CarefullyPut(conn, &err)
+
+		reply := conn.Cmd(ctx, "PING")
+		if reply.Err != nil {
+			return reply.Err
+		}
+		s, err := reply.Str()
+		if err != nil {
+			return err
+		}
+		if s != "PONG" {
+			return fmt.Errorf("unexpected PING reply: %s", s)
+		}
+		return nil
+	})
+}
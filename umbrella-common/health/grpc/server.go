@@ -0,0 +1,235 @@
+package grpchealth
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"umbrella-go/umbrella-common/health"
+)
+
+const defaultWatchInterval = 5 * time.Second
+
+// Server 实现grpc.health.v1.Health，服务状态由health子系统中注册的checker决定
+// 空服务名("")表示整个服务，对应health的readiness检查
+type Server struct {
+	health   *health.Health
+	interval time.Duration
+
+	mu       sync.RWMutex
+	services map[string][]string
+
+	// 同一个service的所有Watch共用一个轮询goroutine
+	watchMu sync.Mutex
+	watches map[string]*watchGroup
+
+	shutdownOnce sync.Once
+	shutdown     chan struct{}
+}
+
+type watchGroup struct {
+	watchers map[chan healthpb.HealthCheckResponse_ServingStatus]struct{}
+	last     healthpb.HealthCheckResponse_ServingStatus
+	stop     chan struct{}
+}
+
+type Option func(*Server)
+
+// WithWatchInterval Watch时重新检查状态的间隔，默认5s
+func WithWatchInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.interval = interval
+	}
+}
+
+// NewServer h为空时使用health.Default
+func NewServer(h *health.Health, opts ...Option) *Server {
+	if h == nil {
+		h = health.Default
+	}
+	s := &Server{
+		health:   h,
+		interval: defaultWatchInterval,
+		services: make(map[string][]string),
+		watches:  make(map[string]*watchGroup),
+		shutdown: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register 将服务注册到grpc.Server上
+func (s *Server) Register(gs *grpc.Server) {
+	healthpb.RegisterHealthServer(gs, s)
+}
+
+// SetServiceDependencies 设置service(如"pkg.Service")依赖的checker名称，
+// 所有依赖的checker都通过时service才是SERVING
+func (s *Server) SetServiceDependencies(service string, checkers ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.services[service] = checkers
+}
+
+// Shutdown 优雅关闭时调用，所有服务立即变为NOT_SERVING，Watch发送NOT_SERVING后结束
+func (s *Server) Shutdown() {
+	s.shutdownOnce.Do(func() {
+		close(s.shutdown)
+	})
+}
+
+func (s *Server) isShutdown() bool {
+	select {
+	case <-s.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Server) status(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	if service == "" {
+		if s.isShutdown() {
+			return healthpb.HealthCheckResponse_NOT_SERVING, true
+		}
+		ready := s.health.Ready(ctx)
+		if ready.Up() {
+			return healthpb.HealthCheckResponse_SERVING, true
+		}
+		return healthpb.HealthCheckResponse_NOT_SERVING, true
+	}
+
+	s.mu.RLock()
+	deps, ok := s.services[service]
+	s.mu.RUnlock()
+	if !ok {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, false
+	}
+
+	if s.isShutdown() || !s.health.IsReady() {
+		return healthpb.HealthCheckResponse_NOT_SERVING, true
+	}
+	for _, name := range deps {
+		result, ok := s.health.CheckOne(ctx, name)
+		if !ok || result.Status != health.StatusUp {
+			return healthpb.HealthCheckResponse_NOT_SERVING, true
+		}
+	}
+	return healthpb.HealthCheckResponse_SERVING, true
+}
+
+func (s *Server) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	st, ok := s.status(ctx, req.Service)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service: %s", req.Service)
+	}
+	return &healthpb.HealthCheckResponse{Status: st}, nil
+}
+
+// Watch 首先发送当前状态，之后状态变化时才发送
+func (s *Server) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx := stream.Context()
+	ch := s.subscribe(req.Service)
+	defer s.unsubscribe(req.Service, ch)
+
+	var last healthpb.HealthCheckResponse_ServingStatus = -1
+	send := func(st healthpb.HealthCheckResponse_ServingStatus) error {
+		if st == last {
+			return nil
+		}
+		last = st
+		return stream.Send(&healthpb.HealthCheckResponse{Status: st})
+	}
+	for {
+		select {
+		case st := <-ch:
+			if err := send(st); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		case <-s.shutdown:
+			if err := send(healthpb.HealthCheckResponse_NOT_SERVING); err != nil {
+				return err
+			}
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
+
+// subscribe 第一个Watch该service时启动轮询，之后的Watch立即收到最近一次的状态
+func (s *Server) subscribe(service string) chan healthpb.HealthCheckResponse_ServingStatus {
+	ch := make(chan healthpb.HealthCheckResponse_ServingStatus, 1)
+
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	g, ok := s.watches[service]
+	if !ok {
+		g = &watchGroup{
+			watchers: make(map[chan healthpb.HealthCheckResponse_ServingStatus]struct{}),
+			last:     -1,
+			stop:     make(chan struct{}),
+		}
+		s.watches[service] = g
+		go s.poll(service, g)
+	}
+	g.watchers[ch] = struct{}{}
+	if g.last != -1 {
+		ch <- g.last
+	}
+	return ch
+}
+
+// unsubscribe 最后一个Watch结束时停止轮询
+func (s *Server) unsubscribe(service string, ch chan healthpb.HealthCheckResponse_ServingStatus) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	g := s.watches[service]
+	delete(g.watchers, ch)
+	if len(g.watchers) == 0 {
+		close(g.stop)
+		delete(s.watches, service)
+	}
+}
+
+func (s *Server) poll(service string, g *watchGroup) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		st, _ := s.status(context.Background(), service)
+		s.broadcast(g, st)
+
+		select {
+		case <-g.stop:
+			return
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// broadcast 状态变化时通知所有Watch，每个Watch只保留最新的状态
+func (s *Server) broadcast(g *watchGroup, st healthpb.HealthCheckResponse_ServingStatus) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if st == g.last {
+		return
+	}
+	g.last = st
+	for ch := range g.watchers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- st
+	}
+}
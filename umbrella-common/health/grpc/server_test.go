@@ -0,0 +1,169 @@
+package grpchealth
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"umbrella-go/umbrella-common/health"
+)
+
+type fakeWatchServer struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan healthpb.HealthCheckResponse_ServingStatus
+}
+
+func newFakeWatchServer(ctx context.Context) *fakeWatchServer {
+	return &fakeWatchServer{ctx: ctx, sent: make(chan healthpb.HealthCheckResponse_ServingStatus, 10)}
+}
+
+func (s *fakeWatchServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeWatchServer) Send(resp *healthpb.HealthCheckResponse) error {
+	s.sent <- resp.Status
+	return nil
+}
+
+func (s *fakeWatchServer) next(t *testing.T) healthpb.HealthCheckResponse_ServingStatus {
+	select {
+	case st := <-s.sent:
+		return st
+	case <-time.After(time.Second):
+		t.Fatal("no status received")
+		return -1
+	}
+}
+
+func watch(s *Server, service string, stream *fakeWatchServer) chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Watch(&healthpb.HealthCheckRequest{Service: service}, stream)
+	}()
+	return done
+}
+
+func TestCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	h := health.New()
+	var dbErr atomic.Value
+	dbErr.Store("")
+	h.Register("db", health.CheckerFunc(func(ctx context.Context) error {
+		if msg := dbErr.Load().(string); msg != "" {
+			return errors.New(msg)
+		}
+		return nil
+	}))
+	s := NewServer(h)
+	s.SetServiceDependencies("test.Echo", "db")
+	ctx := context.Background()
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := s.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if !assert.Nil(err) {
+			return -1
+		}
+		return resp.Status
+	}
+
+	assert.Equal(healthpb.HealthCheckResponse_NOT_SERVING, check(""))
+	assert.Equal(healthpb.HealthCheckResponse_NOT_SERVING, check("test.Echo"))
+
+	h.SetReady(true)
+	assert.Equal(healthpb.HealthCheckResponse_SERVING, check(""))
+	assert.Equal(healthpb.HealthCheckResponse_SERVING, check("test.Echo"))
+
+	dbErr.Store("down")
+	assert.Equal(healthpb.HealthCheckResponse_NOT_SERVING, check(""))
+	assert.Equal(healthpb.HealthCheckResponse_NOT_SERVING, check("test.Echo"))
+
+	_, err := s.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown.Service"})
+	assert.Equal(codes.NotFound, status.Code(err))
+
+	dbErr.Store("")
+	s.Shutdown()
+	assert.Equal(healthpb.HealthCheckResponse_NOT_SERVING, check(""))
+	assert.Equal(healthpb.HealthCheckResponse_NOT_SERVING, check("test.Echo"))
+}
+
+func TestWatch(t *testing.T) {
+	assert := assert.New(t)
+
+	h := health.New()
+	s := NewServer(h, WithWatchInterval(10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := newFakeWatchServer(ctx)
+	done := watch(s, "", stream)
+	assert.Equal(healthpb.HealthCheckResponse_NOT_SERVING, stream.next(t))
+
+	h.SetReady(true)
+	assert.Equal(healthpb.HealthCheckResponse_SERVING, stream.next(t))
+	h.SetReady(false)
+	assert.Equal(healthpb.HealthCheckResponse_NOT_SERVING, stream.next(t))
+
+	cancel()
+	assert.Equal(codes.Canceled, status.Code(<-done))
+
+	// 未知的service返回SERVICE_UNKNOWN，之后注册了依赖也能感知到
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	stream = newFakeWatchServer(ctx)
+	done = watch(s, "test.Echo", stream)
+	assert.Equal(healthpb.HealthCheckResponse_SERVICE_UNKNOWN, stream.next(t))
+	h.SetReady(true)
+	s.SetServiceDependencies("test.Echo")
+	assert.Equal(healthpb.HealthCheckResponse_SERVING, stream.next(t))
+
+	// Shutdown时发送NOT_SERVING后结束
+	s.Shutdown()
+	assert.Equal(healthpb.HealthCheckResponse_NOT_SERVING, stream.next(t))
+	select {
+	case err := <-done:
+		assert.Equal(codes.Unavailable, status.Code(err))
+	case <-time.After(time.Second):
+		t.Fatal("Watch did not end after Shutdown")
+	}
+}
+
+func TestWatchSharesPolling(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	h := health.New()
+	h.SetReady(true)
+	h.Register("db", health.CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}))
+	s := NewServer(h, WithWatchInterval(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var dones []chan error
+	for i := 0; i < 3; i++ {
+		stream := newFakeWatchServer(ctx)
+		dones = append(dones, watch(s, "", stream))
+		assert.Equal(healthpb.HealthCheckResponse_SERVING, stream.next(t))
+	}
+	// 多个Watch只执行一次readiness检查
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+
+	cancel()
+	for _, done := range dones {
+		<-done
+	}
+	s.watchMu.Lock()
+	assert.Equal(0, len(s.watches))
+	s.watchMu.Unlock()
+}
@@ -0,0 +1,38 @@
+package health
+
+import (
+	"net/http"
+
+	"umbrella-go/umbrella-common/json"
+	"umbrella-go/umbrella-common/monitor"
+)
+
+func init() {
+	monitor.MonitorHandlers["/internal/health/live"] = Default.LiveHandler()
+	monitor.MonitorHandlers["/internal/health/ready"] = Default.ReadyHandler()
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	bs, _ := json.Marshal(report)
+	w.Header().Set("Content-Type", "application/json")
+	if report.Up() {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(bs)
+}
+
+func (h *Health) LiveHandler() http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, h.Live(r.Context()))
+	}
+	return http.HandlerFunc(fn)
+}
+
+func (h *Health) ReadyHandler() http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, h.Ready(r.Context()))
+	}
+	return http.HandlerFunc(fn)
+}
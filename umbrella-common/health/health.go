@@ -0,0 +1,261 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	defaultTimeout = time.Second
+)
+
+// Kind 决定checker参与哪类检查
+type Kind int
+
+const (
+	// Readiness 依赖不可用时服务不应接收流量，但不需要重启，如数据库、Redis
+	Readiness Kind = iota
+	// Liveness 检查失败说明进程已无法恢复，需要重启
+	Liveness
+)
+
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type Option func(*registeredChecker)
+
+// WithTimeout 单次检查的超时时长，默认1s
+func WithTimeout(timeout time.Duration) Option {
+	return func(rc *registeredChecker) {
+		rc.timeout = timeout
+	}
+}
+
+// WithCacheTTL 在ttl内复用上次的检查结果，避免频繁的探测请求压垮依赖
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(rc *registeredChecker) {
+		rc.cacheTTL = ttl
+	}
+}
+
+func WithKind(kind Kind) Option {
+	return func(rc *registeredChecker) {
+		rc.kind = kind
+	}
+}
+
+type CheckResult struct {
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Ready  *bool                  `json:"ready,omitempty"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+func (r Report) Up() bool {
+	return r.Status == StatusUp
+}
+
+type registeredChecker struct {
+	name     string
+	checker  Checker
+	kind     Kind
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu     sync.Mutex
+	last   CheckResult
+	cached bool
+}
+
+func (rc *registeredChecker) check(ctx context.Context) CheckResult {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.cached && rc.cacheTTL > 0 && time.Since(rc.last.CheckedAt) < rc.cacheTTL {
+		return rc.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rc.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- rc.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Status:     StatusUp,
+		DurationMs: int64(time.Since(start) / time.Millisecond),
+		CheckedAt:  start,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	rc.last = result
+	rc.cached = true
+	return result
+}
+
+type Health struct {
+	mu       sync.RWMutex
+	checkers map[string]*registeredChecker
+	ready    int32
+}
+
+func New() *Health {
+	return &Health{
+		checkers: make(map[string]*registeredChecker),
+	}
+}
+
+// Register 注册名为name的checker，同名的checker会被替换
+func (h *Health) Register(name string, checker Checker, opts ...Option) {
+	rc := &registeredChecker{
+		name:    name,
+		checker: checker,
+		kind:    Readiness,
+		timeout: defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(rc)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers[name] = rc
+}
+
+func (h *Health) Unregister(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.checkers, name)
+}
+
+// SetReady 启动完成前和开始关闭后应设置为false，使readiness检查失败，负载均衡摘除流量
+func (h *Health) SetReady(ready bool) {
+	if ready {
+		atomic.StoreInt32(&h.ready, 1)
+	} else {
+		atomic.StoreInt32(&h.ready, 0)
+	}
+}
+
+func (h *Health) IsReady() bool {
+	return atomic.LoadInt32(&h.ready) == 1
+}
+
+func (h *Health) checkersOf(kind Kind) []*registeredChecker {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var result []*registeredChecker
+	for _, rc := range h.checkers {
+		if rc.kind == kind {
+			result = append(result, rc)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+// 并发执行checkers
+func runCheckers(ctx context.Context, checkers []*registeredChecker) (map[string]CheckResult, bool) {
+	results := make([]CheckResult, len(checkers))
+	var wg sync.WaitGroup
+	for i, rc := range checkers {
+		wg.Add(1)
+		go func(i int, rc *registeredChecker) {
+			defer wg.Done()
+			results[i] = rc.check(ctx)
+		}(i, rc)
+	}
+	wg.Wait()
+
+	up := true
+	checks := make(map[string]CheckResult, len(checkers))
+	for i, rc := range checkers {
+		checks[rc.name] = results[i]
+		if results[i].Status != StatusUp {
+			up = false
+		}
+	}
+	return checks, up
+}
+
+// Live 只执行Liveness类型的checker
+func (h *Health) Live(ctx context.Context) Report {
+	checks, up := runCheckers(ctx, h.checkersOf(Liveness))
+	report := Report{Status: StatusUp, Checks: checks}
+	if !up {
+		report.Status = StatusDown
+	}
+	return report
+}
+
+// Ready 服务已标记为ready且所有Readiness类型的checker都通过时才为up
+func (h *Health) Ready(ctx context.Context) Report {
+	checks, up := runCheckers(ctx, h.checkersOf(Readiness))
+	ready := h.IsReady()
+	report := Report{Status: StatusUp, Ready: &ready, Checks: checks}
+	if !up || !ready {
+		report.Status = StatusDown
+	}
+	return report
+}
+
+// CheckOne 执行指定名称的checker，不存在时返回false
+func (h *Health) CheckOne(ctx context.Context, name string) (CheckResult, bool) {
+	h.mu.RLock()
+	rc, ok := h.checkers[name]
+	h.mu.RUnlock()
+	if !ok {
+		return CheckResult{}, false
+	}
+	return rc.check(ctx), true
+}
+
+// Default 全局默认的Health，通过/internal/health/*对外提供
+var Default = New()
+
+func Register(name string, checker Checker, opts ...Option) {
+	Default.Register(name, checker, opts...)
+}
+
+func Unregister(name string) {
+	Default.Unregister(name)
+}
+
+func SetReady(ready bool) {
+	Default.SetReady(ready)
+}
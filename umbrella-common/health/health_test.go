@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady(t *testing.T) {
+	assert := assert.New(t)
+
+	h := New()
+	h.Register("ok", CheckerFunc(func(ctx context.Context) error { return nil }))
+
+	report := h.Ready(context.Background())
+	assert.False(report.Up())
+
+	h.SetReady(true)
+	report = h.Ready(context.Background())
+	assert.True(report.Up())
+	assert.Equal(StatusUp, report.Checks["ok"].Status)
+
+	h.Register("broken", CheckerFunc(func(ctx context.Context) error { return errors.New("broken") }))
+	report = h.Ready(context.Background())
+	assert.False(report.Up())
+	assert.Equal("broken", report.Checks["broken"].Error)
+
+	// readiness的checker不影响liveness
+	assert.True(h.Live(context.Background()).Up())
+}
+
+func TestCheckerTimeoutAndCache(t *testing.T) {
+	assert := assert.New(t)
+
+	var calls int32
+	h := New()
+	h.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}), WithTimeout(10*time.Millisecond), WithCacheTTL(time.Minute), WithKind(Liveness))
+
+	report := h.Live(context.Background())
+	assert.False(report.Up())
+	assert.Equal(context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+
+	h.Live(context.Background())
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestReadyHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	h := New()
+	w := httptest.NewRecorder()
+	h.ReadyHandler().ServeHTTP(w, httptest.NewRequest("GET", "/internal/health/ready", nil))
+	assert.Equal(http.StatusServiceUnavailable, w.Code)
+
+	h.SetReady(true)
+	w = httptest.NewRecorder()
+	h.ReadyHandler().ServeHTTP(w, httptest.NewRequest("GET", "/internal/health/ready", nil))
+	assert.Equal(http.StatusOK, w.Code)
+}
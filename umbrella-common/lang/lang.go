@@ -1,7 +1,6 @@
 package lang
 
 import (
-	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
@@ -26,14 +25,12 @@ func ParseAcceptLanguage(acceptLang string) []LangQPair {
 	var results []LangQPair
 
 	items := strings.Split(acceptLang, ",")
-	fmt.Printf("%v\n", items)
 	for _, langQ := range items {
 		langQ = strings.Trim(langQ, " ")
 		if langQ == "" {
 			continue
 		}
 		langPair := strings.Split(langQ, ";")
-		fmt.Printf("%v\n", langPair)
 		if len(langPair) == 1 {
 			results = append(results, LangQPair{langPair[0], 1})
 		} else if len(langPair) == 2 {
@@ -42,7 +39,6 @@ func ParseAcceptLanguage(acceptLang string) []LangQPair {
 				err    error
 			)
 			qPair := strings.Split(langPair[1], "=")
-			fmt.Printf("%v\n", qPair)
 			if len(qPair) >= 2 {
 				if qValue, err = strconv.ParseFloat(qPair[1], 64); err != nil {
 					qValue = 1
@@ -100,6 +96,20 @@ func FromIncomingContext(ctx context.Context) []string {
 	return append(languages, defaultLanguage)
 }
 
+// 从Context中取调用方的语言，优先取Incoming Metadata，没有时返回nil而不是默认语言
+func FromContext(ctx context.Context) []string {
+	for _, fromContext := range []func(context.Context) (metadata.MD, bool){metadata.FromIncomingContext, metadata.FromOutgoingContext} {
+		md, ok := fromContext(ctx)
+		if !ok {
+			continue
+		}
+		if languages := md[metadataLanguageKey]; len(languages) > 0 {
+			return languages
+		}
+	}
+	return nil
+}
+
 // 对context的outgoing metadata填充languages
 func ContextSetLanguages(ctx context.Context, languages []string) context.Context {
 	keyValues := make([]string, 0, len(languages)*2)
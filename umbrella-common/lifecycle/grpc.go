@@ -0,0 +1,45 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"google.golang.org/grpc"
+
+	"umbrella-go/umbrella-common/log"
+)
+
+// GRPCServer 关闭时先GracefulStop等待进行中的RPC结束，超过时限后强制Stop
+func (l *Lifecycle) GRPCServer(name string, srv *grpc.Server, addr string) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			l.logger.Info(ctx, "start grpc server", log.String("name", name), log.String("listen", ln.Addr().String()))
+			go func() {
+				if err := srv.Serve(ln); err != nil && err != grpc.ErrServerStopped {
+					l.Fail(errors.New(name + ": " + err.Error()))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				srv.Stop()
+				return ctx.Err()
+			}
+		},
+	}
+}
@@ -0,0 +1,236 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"umbrella-go/umbrella-common/health"
+	"umbrella-go/umbrella-common/log"
+)
+
+// Hook 启动时按添加的顺序执行OnStart，退出时按相反的顺序执行OnStop
+// OnStart不能阻塞，长期运行的服务应在goroutine中运行并通过Lifecycle.Fail报告错误
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+type Config struct {
+	StartTimeout time.Duration // 为0时使用15秒
+	StopTimeout  time.Duration // 关闭所有Hook的总时限，为0时使用30秒
+	// DrainDelay 收到信号后先将readiness置为未就绪，等待负载均衡摘除流量后再开始关闭，
+	// 为0时不等待
+	DrainDelay time.Duration
+}
+
+type Lifecycle struct {
+	config Config
+	logger *log.Logger
+
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+
+	failOnce sync.Once
+	failed   chan error
+}
+
+func New(config Config) *Lifecycle {
+	if config.StartTimeout <= 0 {
+		config.StartTimeout = 15 * time.Second
+	}
+	if config.StopTimeout <= 0 {
+		config.StopTimeout = 30 * time.Second
+	}
+	return &Lifecycle{
+		config: config,
+		logger: log.Named("lifecycle"),
+		failed: make(chan error, 1),
+	}
+}
+
+func (l *Lifecycle) Append(hooks ...Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hooks...)
+}
+
+// Fail 报告运行中的致命错误(如服务意外退出)，Run会随之开始关闭流程
+func (l *Lifecycle) Fail(err error) {
+	l.failOnce.Do(func() {
+		l.failed <- err
+	})
+}
+
+// Start 按顺序执行OnStart，任何一个失败时按相反的顺序关闭已经启动的Hook
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := l.hooks
+	l.mu.Unlock()
+
+	for i := l.started; i < len(hooks); i++ {
+		hook := hooks[i]
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				l.logger.Error(ctx, "start failed", log.String("hook", hook.Name), log.Err(err))
+				l.Stop(ctx)
+				return err
+			}
+		}
+		l.started = i + 1
+		l.logger.Info(ctx, "started", log.String("hook", hook.Name))
+	}
+	return nil
+}
+
+// Stop 按相反的顺序执行已启动Hook的OnStop，单个失败不影响后续的关闭
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := l.hooks[:l.started]
+	l.started = 0
+	l.mu.Unlock()
+
+	var errs []string
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if hook.OnStop == nil {
+			continue
+		}
+		if err := hook.OnStop(ctx); err != nil {
+			l.logger.Error(ctx, "stop failed", log.String("hook", hook.Name), log.Err(err))
+			errs = append(errs, hook.Name+": "+err.Error())
+			continue
+		}
+		l.logger.Info(ctx, "stopped", log.String("hook", hook.Name))
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Run 启动所有Hook后将服务置为就绪，收到SIGINT/SIGTERM或Fail报告错误时
+// 先置为未就绪，等待DrainDelay后在StopTimeout内关闭所有Hook
+func (l *Lifecycle) Run() error {
+	startCtx, cancel := context.WithTimeout(context.Background(), l.config.StartTimeout)
+	err := l.Start(startCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+	health.SetReady(true)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ctx := context.Background()
+	var runErr error
+	select {
+	case sig := <-signals:
+		l.logger.Info(ctx, "received signal, shutting down", log.String("signal", sig.String()))
+	case runErr = <-l.failed:
+		l.logger.Error(ctx, "fatal error, shutting down", log.Err(runErr))
+	}
+
+	health.SetReady(false)
+	if l.config.DrainDelay > 0 {
+		select {
+		case <-time.After(l.config.DrainDelay):
+		case sig := <-signals:
+			l.logger.Warn(ctx, "received second signal, skip draining", log.String("signal", sig.String()))
+		}
+	}
+
+	stopCtx, cancel := context.WithTimeout(ctx, l.config.StopTimeout)
+	defer cancel()
+	if err := l.Stop(stopCtx); err != nil && runErr == nil {
+		runErr = err
+	}
+	return runErr
+}
+
+// HTTPServer 启动时同步监听端口以便尽早发现地址冲突，关闭时等待进行中的请求处理完成，
+// 超过时限后强制关闭连接，srv.TLSConfig不为空时使用TLS
+func (l *Lifecycle) HTTPServer(name string, srv *http.Server) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(ctx context.Context) error {
+			addr := srv.Addr
+			if addr == "" {
+				addr = ":http"
+			}
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			l.logger.Info(ctx, "start http server", log.String("name", name), log.String("listen", ln.Addr().String()),
+				log.Bool("tls", srv.TLSConfig != nil))
+			go func() {
+				serve := srv.Serve
+				if srv.TLSConfig != nil {
+					// 证书由TLSConfig.GetCertificate提供
+					serve = func(ln net.Listener) error { return srv.ServeTLS(ln, "", "") }
+				}
+				if err := serve(ln); err != nil && err != http.ErrServerClosed {
+					l.Fail(errors.New(name + ": " + err.Error()))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if err := srv.Shutdown(ctx); err != nil {
+				srv.Close()
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// Server 可以由Lifecycle管理的服务，Shutdown之后Serve应返回http.ErrServerClosed
+type Server interface {
+	Serve(ln net.Listener) error
+	Shutdown(ctx context.Context) error
+}
+
+// Server 与HTTPServer相同，用于server.Mux等自行实现优雅关闭的服务
+func (l *Lifecycle) Server(name, addr string, srv Server) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			l.logger.Info(ctx, "start server", log.String("name", name), log.String("listen", ln.Addr().String()))
+			go func() {
+				if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+					l.Fail(errors.New(name + ": " + err.Error()))
+				}
+			}()
+			return nil
+		},
+		OnStop: srv.Shutdown,
+	}
+}
+
+// Closer 只在退出时执行的Hook，用于关闭数据库、Redis连接池和订阅等资源
+func Closer(name string, close func() error) Hook {
+	return Hook{
+		Name: name,
+		OnStop: func(ctx context.Context) error {
+			return close()
+		},
+	}
+}
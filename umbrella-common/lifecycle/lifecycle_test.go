@@ -0,0 +1,47 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartStopOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	var events []string
+	hook := func(name string, startErr error) Hook {
+		return Hook{
+			Name: name,
+			OnStart: func(ctx context.Context) error {
+				events = append(events, "start "+name)
+				return startErr
+			},
+			OnStop: func(ctx context.Context) error {
+				events = append(events, "stop "+name)
+				return nil
+			},
+		}
+	}
+
+	l := New(Config{})
+	l.Append(hook("db", nil), hook("redis", nil), Closer("pubsub", func() error {
+		events = append(events, "close pubsub")
+		return nil
+	}), hook("http", nil))
+	assert.Nil(l.Start(context.Background()))
+	assert.Nil(l.Stop(context.Background()))
+	assert.Equal([]string{
+		"start db", "start redis", "start http",
+		"stop http", "close pubsub", "stop redis", "stop db",
+	}, events)
+
+	// 启动失败时只关闭已经启动的Hook
+	events = nil
+	l = New(Config{})
+	l.Append(hook("db", nil), hook("http", errors.New("address in use")), hook("grpc", nil))
+	assert.NotNil(l.Start(context.Background()))
+	assert.Equal([]string{"start db", "start http", "stop db"}, events)
+}
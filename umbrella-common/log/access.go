@@ -0,0 +1,39 @@
+package log
+
+import (
+	"math/rand"
+	"time"
+)
+
+// AccessLogConfig HTTP和gRPC访问日志共用的配置
+type AccessLogConfig struct {
+	Logger            *Logger       // 为空时使用Named("access")
+	SuccessSampleRate float64       // 成功请求的采样率，取值(0, 1]，为0时全部记录
+	SlowThreshold     time.Duration // 耗时超过该值的请求总是记录，为0时不判断慢请求
+}
+
+// AccessLogger 返回输出访问日志的Logger
+func (c AccessLogConfig) AccessLogger() *Logger {
+	if c.Logger == nil {
+		return Named("access")
+	}
+	return c.Logger
+}
+
+// AccessLevel 决定一条访问日志的级别：serverError为Error级别，failed(客户端错误或业务错误)和慢请求为Warn级别，
+// 成功的请求按SuccessSampleRate采样，ok为false时不记录
+func (c AccessLogConfig) AccessLevel(serverError, failed bool, cost time.Duration) (level Level, slow bool, ok bool) {
+	slow = c.SlowThreshold > 0 && cost >= c.SlowThreshold
+	switch {
+	case serverError:
+		return ErrorLevel, slow, true
+	case failed || slow:
+		return WarnLevel, slow, true
+	}
+
+	rate := c.SuccessSampleRate
+	if rate > 0 && rate < 1 && rand.Float64() >= rate {
+		return InfoLevel, slow, false
+	}
+	return InfoLevel, slow, true
+}
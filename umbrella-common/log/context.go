@@ -0,0 +1,58 @@
+package log
+
+import (
+	"context"
+
+	"umbrella-go/umbrella-common/caller"
+	"umbrella-go/umbrella-common/lang"
+)
+
+type requestIDKey struct{}
+
+type userIDKey struct{}
+
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+func ContextWithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, id)
+}
+
+func UserIDFromContext(ctx context.Context) string {
+	id, ok := ctx.Value(userIDKey{}).(string)
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+// contextFields 从context中取出调用方、请求ID、语言和用户ID，没有的字段不输出
+func contextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+
+	var fields []Field
+	if name := caller.CallerNameFromContext(ctx); name != "" {
+		fields = append(fields, String("caller", name))
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		fields = append(fields, String("request_id", id))
+	}
+	if languages := lang.FromContext(ctx); len(languages) > 0 {
+		fields = append(fields, Strings("languages", languages))
+	}
+	if id := UserIDFromContext(ctx); id != "" {
+		fields = append(fields, String("user_id", id))
+	}
+	return fields
+}
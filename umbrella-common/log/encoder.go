@@ -0,0 +1,107 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"umbrella-go/umbrella-common/json"
+)
+
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+)
+
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Logger  string
+	Message string
+	Fields  []Field
+}
+
+type Encoder interface {
+	Encode(entry *Entry) []byte
+}
+
+func newEncoder(encoding string) (Encoder, error) {
+	switch encoding {
+	case EncodingJSON, "":
+		return jsonEncoder{}, nil
+	case EncodingConsole:
+		return consoleEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown log encoding: %q", encoding)
+	}
+}
+
+// jsonEncoder 每条日志输出为一行JSON，固定字段在前，便于日志采集
+type jsonEncoder struct{}
+
+func (jsonEncoder) Encode(entry *Entry) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString(`{"time":`)
+	buf.WriteString(strconv.Quote(entry.Time.Format(time.RFC3339Nano)))
+	buf.WriteString(`,"level":`)
+	buf.WriteString(strconv.Quote(entry.Level.String()))
+	if entry.Logger != "" {
+		buf.WriteString(`,"logger":`)
+		writeJSONValue(buf, entry.Logger)
+	}
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, entry.Message)
+	for _, field := range entry.Fields {
+		buf.WriteByte(',')
+		writeJSONValue(buf, field.Key)
+		buf.WriteByte(':')
+		writeJSONValue(buf, field.Value)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(data)
+}
+
+// consoleEncoder 面向开发环境的可读格式：时间 级别 logger 消息 key=value...
+type consoleEncoder struct{}
+
+func (consoleEncoder) Encode(entry *Entry) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString(entry.Time.Format("2006-01-02T15:04:05.000Z0700"))
+	buf.WriteByte('\t')
+	buf.WriteString(strings.ToUpper(entry.Level.String()))
+	buf.WriteByte('\t')
+	if entry.Logger != "" {
+		buf.WriteString(entry.Logger)
+		buf.WriteByte('\t')
+	}
+	buf.WriteString(entry.Message)
+	for _, field := range entry.Fields {
+		buf.WriteByte(' ')
+		buf.WriteString(field.Key)
+		buf.WriteByte('=')
+		switch v := field.Value.(type) {
+		case string:
+			if strings.ContainsAny(v, " \t\n\"=") {
+				buf.WriteString(strconv.Quote(v))
+			} else {
+				buf.WriteString(v)
+			}
+		case nil:
+			buf.WriteString("null")
+		default:
+			fmt.Fprint(buf, v)
+		}
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
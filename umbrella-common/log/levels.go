@@ -0,0 +1,231 @@
+package log
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"umbrella-go/umbrella-common/caller"
+)
+
+// levelOverride 临时调整的日志级别，过期后自动恢复为配置的级别
+type levelOverride struct {
+	level   Level
+	expires time.Time // 为零值时不过期
+}
+
+func (o levelOverride) expired(now time.Time) bool {
+	return !o.expires.IsZero() && now.After(o.expires)
+}
+
+// overrides 全局、按logger名称以及按调用方的日志级别调整
+// 调用方的优先级最高，其次是logger名称(按前缀匹配，"redis"同时作用于"redis.pool")，最后是全局
+type overrides struct {
+	active int32
+
+	mu      sync.RWMutex
+	global  *levelOverride
+	loggers map[string]levelOverride
+	callers map[string]levelOverride
+	timer   *time.Timer // 在最早的调整过期时执行gc
+}
+
+func (o *overrides) level(name string, ctx context.Context) (Level, bool) {
+	if atomic.LoadInt32(&o.active) == 0 {
+		return 0, false
+	}
+
+	now := time.Now()
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if ctx != nil && len(o.callers) > 0 {
+		if c := caller.CallerNameFromContext(ctx); c != "" {
+			if lo, ok := o.callers[c]; ok && !lo.expired(now) {
+				return lo.level, true
+			}
+		}
+	}
+
+	for n := name; n != ""; n = parentName(n) {
+		if lo, ok := o.loggers[n]; ok && !lo.expired(now) {
+			return lo.level, true
+		}
+	}
+
+	if o.global != nil && !o.global.expired(now) {
+		return o.global.level, true
+	}
+	return 0, false
+}
+
+func parentName(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+func newOverride(level Level, ttl time.Duration) levelOverride {
+	lo := levelOverride{level: level}
+	if ttl > 0 {
+		lo.expires = time.Now().Add(ttl)
+	}
+	return lo
+}
+
+func (o *overrides) set(fn func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.loggers == nil {
+		o.loggers = make(map[string]levelOverride)
+		o.callers = make(map[string]levelOverride)
+	}
+	fn()
+	o.gc(time.Now())
+}
+
+// gc 清理已经过期的调整，全部过期后Log不再需要加锁检查
+// 还有会过期的调整时，在最早的过期时间再次执行gc
+func (o *overrides) gc(now time.Time) {
+	var next time.Time
+	keep := func(lo levelOverride) bool {
+		if lo.expired(now) {
+			return false
+		}
+		if !lo.expires.IsZero() && (next.IsZero() || lo.expires.Before(next)) {
+			next = lo.expires
+		}
+		return true
+	}
+
+	if o.global != nil && !keep(*o.global) {
+		o.global = nil
+	}
+	for k, v := range o.loggers {
+		if !keep(v) {
+			delete(o.loggers, k)
+		}
+	}
+	for k, v := range o.callers {
+		if !keep(v) {
+			delete(o.callers, k)
+		}
+	}
+
+	active := int32(0)
+	if o.global != nil || len(o.loggers) > 0 || len(o.callers) > 0 {
+		active = 1
+	}
+	atomic.StoreInt32(&o.active, active)
+
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	if !next.IsZero() {
+		// expired在超过过期时间之后才返回true
+		o.timer = time.AfterFunc(next.Sub(now)+time.Millisecond, func() {
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			o.gc(time.Now())
+		})
+	}
+}
+
+// SetGlobalLevel 临时调整全局的日志级别，ttl为0时不自动恢复
+func SetGlobalLevel(level Level, ttl time.Duration) {
+	o := &L.core.overrides
+	o.set(func() {
+		lo := newOverride(level, ttl)
+		o.global = &lo
+	})
+}
+
+// SetLoggerLevel 临时调整名称为name及其子logger的日志级别，ttl为0时不自动恢复
+func SetLoggerLevel(name string, level Level, ttl time.Duration) {
+	o := &L.core.overrides
+	o.set(func() {
+		o.loggers[name] = newOverride(level, ttl)
+	})
+}
+
+// SetCallerLevel 临时调整来自调用方callerName的请求的日志级别，ttl为0时不自动恢复
+func SetCallerLevel(callerName string, level Level, ttl time.Duration) {
+	o := &L.core.overrides
+	o.set(func() {
+		o.callers[callerName] = newOverride(level, ttl)
+	})
+}
+
+// ResetLevel logger和callerName都为空时取消全局的调整，否则取消对应的调整
+func ResetLevel(logger, callerName string) {
+	o := &L.core.overrides
+	o.set(func() {
+		if logger == "" && callerName == "" {
+			o.global = nil
+		}
+		if logger != "" {
+			delete(o.loggers, logger)
+		}
+		if callerName != "" {
+			delete(o.callers, callerName)
+		}
+	})
+}
+
+type LevelOverride struct {
+	Name    string     `json:"name,omitempty"`
+	Level   Level      `json:"level"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+type LevelStatus struct {
+	Level   Level           `json:"level"`
+	Global  *LevelOverride  `json:"global,omitempty"`
+	Loggers []LevelOverride `json:"loggers"`
+	Callers []LevelOverride `json:"callers"`
+}
+
+// Levels 返回配置的日志级别以及当前生效的调整
+func Levels() LevelStatus {
+	o := &L.core.overrides
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.gc(time.Now())
+
+	status := LevelStatus{
+		Level:   L.Level(),
+		Loggers: exportOverrides(o.loggers),
+		Callers: exportOverrides(o.callers),
+	}
+	if o.global != nil {
+		global := exportOverride("", *o.global)
+		status.Global = &global
+	}
+	return status
+}
+
+func exportOverride(name string, lo levelOverride) LevelOverride {
+	result := LevelOverride{Name: name, Level: lo.level}
+	if !lo.expires.IsZero() {
+		expires := lo.expires
+		result.Expires = &expires
+	}
+	return result
+}
+
+func exportOverrides(m map[string]levelOverride) []LevelOverride {
+	result := make([]LevelOverride, 0, len(m))
+	for name, lo := range m {
+		result = append(result, exportOverride(name, lo))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,221 @@
+package log
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Config struct {
+	Level    Level
+	Encoding string // json或console，默认json
+	Output   string // stdout、stderr或文件路径，默认stderr
+	Sampling *SamplingConfig
+}
+
+// core 保存所有Logger共享的配置，Init时原地更新，
+// 因此包级变量中提前创建的Logger也会使用新的配置
+type core struct {
+	level     int32
+	overrides overrides
+
+	mu      sync.Mutex
+	encoder Encoder
+	out     io.Writer
+	closer  io.Closer
+	sampler *sampler
+}
+
+func newCore() *core {
+	return &core{level: int32(InfoLevel), encoder: jsonEncoder{}, out: os.Stderr}
+}
+
+func (c *core) configure(config Config) error {
+	encoder, err := newEncoder(config.Encoding)
+	if err != nil {
+		return err
+	}
+	out, closer, err := openOutput(config.Output)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	oldCloser := c.closer
+	c.encoder = encoder
+	c.out = out
+	c.closer = closer
+	c.sampler = newSampler(config.Sampling)
+	c.mu.Unlock()
+	atomic.StoreInt32(&c.level, int32(config.Level))
+
+	if oldCloser != nil {
+		oldCloser.Close()
+	}
+	return nil
+}
+
+func openOutput(output string) (io.Writer, io.Closer, error) {
+	switch output {
+	case "stderr", "":
+		return os.Stderr, nil, nil
+	case "stdout":
+		return os.Stdout, nil, nil
+	default:
+		f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, err
+		}
+		return f, f, nil
+	}
+}
+
+func (c *core) write(entry *Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.sampler.allow(entry.Time, entry.Level, entry.Message) {
+		return
+	}
+	c.out.Write(c.encoder.Encode(entry))
+}
+
+type Logger struct {
+	core   *core
+	name   string
+	fields []Field
+}
+
+func New(config Config) (*Logger, error) {
+	c := newCore()
+	if err := c.configure(config); err != nil {
+		return nil, err
+	}
+	return &Logger{core: c}, nil
+}
+
+// Named 返回子Logger，名称以"."连接
+func (l *Logger) Named(name string) *Logger {
+	child := *l
+	if l.name == "" {
+		child.name = name
+	} else {
+		child.name = l.name + "." + name
+	}
+	return &child
+}
+
+// With 返回附带固定字段的子Logger
+func (l *Logger) With(fields ...Field) *Logger {
+	child := *l
+	child.fields = make([]Field, 0, len(l.fields)+len(fields))
+	child.fields = append(child.fields, l.fields...)
+	child.fields = append(child.fields, fields...)
+	return &child
+}
+
+func (l *Logger) Name() string {
+	return l.name
+}
+
+func (l *Logger) SetLevel(level Level) {
+	atomic.StoreInt32(&l.core.level, int32(level))
+}
+
+func (l *Logger) Level() Level {
+	return Level(atomic.LoadInt32(&l.core.level))
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return l.enabled(nil, level)
+}
+
+// enabled 优先使用通过SetCallerLevel/SetLoggerLevel/SetGlobalLevel临时调整的级别
+func (l *Logger) enabled(ctx context.Context, level Level) bool {
+	if override, ok := l.core.overrides.level(l.name, ctx); ok {
+		return level >= override
+	}
+	return level >= l.Level()
+}
+
+func (l *Logger) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	if !l.enabled(ctx, level) {
+		return
+	}
+
+	ctxFields := contextFields(ctx)
+	all := make([]Field, 0, len(ctxFields)+len(l.fields)+len(fields))
+	all = append(all, ctxFields...)
+	all = append(all, l.fields...)
+	all = append(all, fields...)
+
+	l.core.write(&Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Logger:  l.name,
+		Message: msg,
+		Fields:  all,
+	})
+}
+
+func (l *Logger) Debug(ctx context.Context, msg string, fields ...Field) {
+	l.Log(ctx, DebugLevel, msg, fields...)
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, fields ...Field) {
+	l.Log(ctx, InfoLevel, msg, fields...)
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, fields ...Field) {
+	l.Log(ctx, WarnLevel, msg, fields...)
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, fields ...Field) {
+	l.Log(ctx, ErrorLevel, msg, fields...)
+}
+
+// Fatal 输出日志后退出进程
+func (l *Logger) Fatal(ctx context.Context, msg string, fields ...Field) {
+	l.Log(ctx, FatalLevel, msg, fields...)
+	os.Exit(1)
+}
+
+// L 全局Logger，未调用Init时以JSON格式输出Info及以上级别的日志到stderr
+var L = &Logger{core: newCore()}
+
+// Init 使用配置更新全局Logger，已经通过L.Named/L.With创建的Logger同样生效
+func Init(config Config) error {
+	return L.core.configure(config)
+}
+
+func Named(name string) *Logger {
+	return L.Named(name)
+}
+
+func With(fields ...Field) *Logger {
+	return L.With(fields...)
+}
+
+func Debug(ctx context.Context, msg string, fields ...Field) {
+	L.Log(ctx, DebugLevel, msg, fields...)
+}
+
+func Info(ctx context.Context, msg string, fields ...Field) {
+	L.Log(ctx, InfoLevel, msg, fields...)
+}
+
+func Warn(ctx context.Context, msg string, fields ...Field) {
+	L.Log(ctx, WarnLevel, msg, fields...)
+}
+
+func Error(ctx context.Context, msg string, fields ...Field) {
+	L.Log(ctx, ErrorLevel, msg, fields...)
+}
+
+func Fatal(ctx context.Context, msg string, fields ...Field) {
+	L.Log(ctx, FatalLevel, msg, fields...)
+	os.Exit(1)
+}
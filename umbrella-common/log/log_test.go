@@ -0,0 +1,138 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"umbrella-go/umbrella-common/caller"
+	"umbrella-go/umbrella-common/json"
+)
+
+func newTestLogger(config Config) (*Logger, *bytes.Buffer) {
+	l, _ := New(config)
+	buf := new(bytes.Buffer)
+	l.core.out = buf
+	return l, buf
+}
+
+func TestJSONEncoderWithContext(t *testing.T) {
+	assert := assert.New(t)
+
+	l, buf := newTestLogger(Config{})
+	ctx := caller.ContextWithCallerName(context.Background(), "web")
+	ctx = ContextWithRequestID(ctx, "req-1")
+	ctx = ContextWithUserID(ctx, "u1")
+
+	l.Named("redis").With(Int("db", 1)).Info(ctx, "connected", String("addr", "127.0.0.1:6379"))
+	l.Debug(ctx, "dropped")
+
+	var entry map[string]interface{}
+	assert.Nil(json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal("info", entry["level"])
+	assert.Equal("redis", entry["logger"])
+	assert.Equal("connected", entry["msg"])
+	assert.Equal("web", entry["caller"])
+	assert.Equal("req-1", entry["request_id"])
+	assert.Equal("u1", entry["user_id"])
+	assert.Equal(float64(1), entry["db"])
+	assert.Equal("127.0.0.1:6379", entry["addr"])
+}
+
+func TestConsoleEncoder(t *testing.T) {
+	assert := assert.New(t)
+
+	l, buf := newTestLogger(Config{Encoding: EncodingConsole, Level: DebugLevel})
+	l.Debug(context.Background(), "hello", String("name", "a b"), Err(nil))
+
+	line := buf.String()
+	assert.Contains(line, "\tDEBUG\thello")
+	assert.True(strings.HasSuffix(line, ` name="a b" error=null`+"\n"))
+}
+
+func TestSampling(t *testing.T) {
+	assert := assert.New(t)
+
+	l, buf := newTestLogger(Config{Sampling: &SamplingConfig{Initial: 2, Thereafter: 3, Tick: time.Hour}})
+	for i := 0; i < 8; i++ {
+		l.Info(context.Background(), "repeated")
+		l.Error(context.Background(), "failed")
+	}
+
+	assert.Equal(4, strings.Count(buf.String(), "repeated"))
+	assert.Equal(8, strings.Count(buf.String(), "failed"))
+}
+
+func TestLevelOverrides(t *testing.T) {
+	assert := assert.New(t)
+	defer func() {
+		ResetLevel("", "")
+		ResetLevel("redis", "web")
+	}()
+
+	redis := L.Named("redis").Named("pool")
+	ctx := caller.ContextWithCallerName(context.Background(), "web")
+
+	assert.False(redis.enabled(context.Background(), DebugLevel))
+
+	SetLoggerLevel("redis", DebugLevel, 0)
+	assert.True(redis.enabled(context.Background(), DebugLevel))
+	assert.False(L.enabled(context.Background(), DebugLevel))
+
+	SetCallerLevel("web", ErrorLevel, 0)
+	assert.False(redis.enabled(ctx, WarnLevel))
+
+	SetGlobalLevel(DebugLevel, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	assert.False(L.enabled(context.Background(), DebugLevel))
+	assert.Nil(Levels().Global)
+	assert.Equal(1, len(Levels().Loggers))
+}
+
+func TestLevelOverrideExpires(t *testing.T) {
+	assert := assert.New(t)
+	defer ResetLevel("", "")
+
+	o := &L.core.overrides
+	SetGlobalLevel(DebugLevel, 10*time.Millisecond)
+	assert.Equal(int32(1), atomic.LoadInt32(&o.active))
+
+	// 过期后不需要再次调用Set*也会清理，Log不再加锁检查
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&o.active) != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(int32(0), atomic.LoadInt32(&o.active))
+	assert.False(L.enabled(context.Background(), DebugLevel))
+}
+
+func TestAccessLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	config := AccessLogConfig{SuccessSampleRate: 1e-9, SlowThreshold: time.Second}
+
+	level, slow, ok := config.AccessLevel(true, true, 0)
+	assert.Equal(ErrorLevel, level)
+	assert.False(slow)
+	assert.True(ok)
+
+	level, _, ok = config.AccessLevel(false, true, 0)
+	assert.Equal(WarnLevel, level)
+	assert.True(ok)
+
+	level, slow, ok = config.AccessLevel(false, false, 2*time.Second)
+	assert.Equal(WarnLevel, level)
+	assert.True(slow)
+	assert.True(ok)
+
+	_, _, ok = config.AccessLevel(false, false, 0)
+	assert.False(ok)
+
+	_, _, ok = AccessLogConfig{}.AccessLevel(false, false, 0)
+	assert.True(ok)
+}
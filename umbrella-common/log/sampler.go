@@ -0,0 +1,64 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+type SamplingConfig struct {
+	Initial    int           // 每个周期内同一级别同一消息前Initial条全部输出
+	Thereafter int           // 超过Initial之后每Thereafter条输出一条，为0时全部丢弃
+	Tick       time.Duration // 统计周期，为0时使用1秒
+}
+
+// sampler 按(级别, 消息)统计每个周期内的日志条数，抑制短时间内大量重复的日志
+// Error及以上级别不采样
+type sampler struct {
+	config SamplingConfig
+
+	mu     sync.Mutex
+	reset  time.Time
+	counts map[samplerKey]int
+}
+
+type samplerKey struct {
+	level   Level
+	message string
+}
+
+func newSampler(config *SamplingConfig) *sampler {
+	if config == nil || config.Initial <= 0 {
+		return nil
+	}
+	c := *config
+	if c.Tick <= 0 {
+		c.Tick = time.Second
+	}
+	return &sampler{config: c, counts: make(map[samplerKey]int)}
+}
+
+func (s *sampler) allow(now time.Time, level Level, message string) bool {
+	if s == nil || level >= ErrorLevel {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.reset) >= s.config.Tick {
+		s.reset = now
+		s.counts = make(map[samplerKey]int, len(s.counts))
+	}
+
+	key := samplerKey{level: level, message: message}
+	n := s.counts[key] + 1
+	s.counts[key] = n
+
+	if n <= s.config.Initial {
+		return true
+	}
+	if s.config.Thereafter <= 0 {
+		return false
+	}
+	return (n-s.config.Initial)%s.config.Thereafter == 0
+}
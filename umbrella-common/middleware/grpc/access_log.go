@@ -0,0 +1,138 @@
+package grpcmiddleware
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"umbrella-go/umbrella-common/log"
+)
+
+// AccessLogConfig 与HTTP访问日志共用配置
+type AccessLogConfig = log.AccessLogConfig
+
+type accessLogger struct {
+	config AccessLogConfig
+	logger *log.Logger
+}
+
+func newAccessLogger(config AccessLogConfig) *accessLogger {
+	return &accessLogger{config: config, logger: config.AccessLogger()}
+}
+
+// isServerError 服务端错误记录为Error级别，其余非OK状态记录为Warn级别
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+func (a *accessLogger) log(ctx context.Context, msg string, fullMethod string, err error, errorCode int, cost time.Duration, fields ...log.Field) {
+	code := grpc.Code(err)
+	level, slow, ok := a.config.AccessLevel(isServerError(code), code != codes.OK || errorCode != 0, cost)
+	if !ok {
+		return
+	}
+
+	remote := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+
+	fields = append([]log.Field{
+		log.String("method", fullMethod),
+		log.String("code", code.String()),
+		log.Int("error_code", errorCode),
+		log.Duration("latency", cost),
+		log.Bool("slow", slow),
+		log.String("remote", remote),
+	}, fields...)
+	if err != nil {
+		fields = append(fields, log.Err(err))
+	}
+	a.logger.Log(ctx, level, msg, fields...)
+}
+
+func messageSize(v interface{}) int {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Size(m)
+	}
+	return 0
+}
+
+// UnaryServerAccessLog 每个请求输出一行访问日志，放在MakeUnaryServerErrorTranslator外层时
+// 从响应的Error中取业务错误码
+func UnaryServerAccessLog(config AccessLogConfig) grpc.UnaryServerInterceptor {
+	a := newAccessLogger(config)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		start := time.Now()
+		defer func() {
+			// handler panic时由外层的recovery转换为codes.Internal，这里按codes.Internal记录后继续panic
+			if p := recover(); p != nil {
+				err = status.Error(codes.Internal, fmt.Sprint(p))
+				defer panic(p)
+			}
+			errorCode := 0
+			if eg, ok := resp.(errorGetter); ok && eg.GetError() != nil {
+				errorCode = int(eg.GetError().Code)
+			}
+			a.log(ctx, "grpc access", info.FullMethod, err, errorCode, time.Now().Sub(start),
+				log.Int("bytes_in", messageSize(req)), log.Int("bytes_out", messageSize(resp)))
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+type accessLogServerStream struct {
+	grpc.ServerStream
+	sent     int64
+	received int64
+}
+
+func (s *accessLogServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.sent, 1)
+	}
+	return err
+}
+
+func (s *accessLogServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.received, 1)
+	}
+	return err
+}
+
+// StreamServerAccessLog 每个stream结束时输出一行访问日志，包含收发的消息数
+func StreamServerAccessLog(config AccessLogConfig) grpc.StreamServerInterceptor {
+	a := newAccessLogger(config)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		as := &accessLogServerStream{ServerStream: ss}
+		start := time.Now()
+		defer func() {
+			// handler panic时由外层的recovery转换为codes.Internal，这里按codes.Internal记录后继续panic
+			if p := recover(); p != nil {
+				err = status.Error(codes.Internal, fmt.Sprint(p))
+				defer panic(p)
+			}
+			a.log(ss.Context(), "grpc stream access", info.FullMethod, err, 0, time.Now().Sub(start),
+				log.Int64("msgs_in", atomic.LoadInt64(&as.received)), log.Int64("msgs_out", atomic.LoadInt64(&as.sent)))
+		}()
+
+		return handler(srv, as)
+	}
+}
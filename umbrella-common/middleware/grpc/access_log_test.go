@@ -0,0 +1,94 @@
+package grpcmiddleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"umbrella-go/umbrella-common/json"
+	"umbrella-go/umbrella-common/log"
+	proto "umbrella-go/umbrella-common/proto"
+)
+
+type errorResp struct {
+	err *proto.Error
+}
+
+func (r errorResp) GetError() *proto.Error {
+	return r.err
+}
+
+func TestUnaryServerAccessLog(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := ioutil.TempFile("", "access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	logger, err := log.New(log.Config{Output: f.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 采样率极低，成功的请求不记录
+	interceptor := UnaryServerAccessLog(AccessLogConfig{Logger: logger, SuccessSampleRate: 1e-9, SlowThreshold: 20 * time.Millisecond})
+	call := func(handler grpc.UnaryHandler) {
+		interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: testMethod}, handler)
+	}
+	call(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return errorResp{}, nil
+	})
+	call(func(ctx context.Context, req interface{}) (interface{}, error) {
+		time.Sleep(30 * time.Millisecond)
+		return errorResp{}, nil
+	})
+	call(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return errorResp{err: &proto.Error{Code: 10001}}, nil
+	})
+	call(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.InvalidArgument, "bad request")
+	})
+	call(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Internal, "failed")
+	})
+
+	data, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatal(err)
+		}
+		logs = append(logs, entry)
+	}
+	if !assert.Equal(4, len(logs)) {
+		return
+	}
+
+	assert.Equal("warn", logs[0]["level"])
+	assert.Equal(true, logs[0]["slow"])
+	assert.Equal(testMethod, logs[0]["method"])
+
+	assert.Equal("warn", logs[1]["level"])
+	assert.Equal(float64(10001), logs[1]["error_code"])
+	assert.Equal("OK", logs[1]["code"])
+
+	assert.Equal("warn", logs[2]["level"])
+	assert.Equal("InvalidArgument", logs[2]["code"])
+
+	assert.Equal("error", logs[3]["level"])
+	assert.Equal("Internal", logs[3]["code"])
+}
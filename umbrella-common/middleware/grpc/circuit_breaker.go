@@ -0,0 +1,186 @@
+package grpcmiddleware
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+var (
+	defaultFailureCodes = []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown}
+
+	ErrCircuitOpen = status.Error(codes.Unavailable, "circuit breaker is open")
+)
+
+type CircuitBreakerPolicy struct {
+	FailureThreshold int           // 连续失败多少次后熔断
+	OpenTimeout      time.Duration // 熔断持续时长，超时后进入半开状态
+	HalfOpenRequests int           // 半开状态下允许同时通过的探测请求数，默认为1
+	FailureCodes     []codes.Code  // 视为失败的状态码，为空时使用defaultFailureCodes
+}
+
+func (p *CircuitBreakerPolicy) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	cs := p.FailureCodes
+	if len(cs) == 0 {
+		cs = defaultFailureCodes
+	}
+	return containsCode(cs, grpc.Code(err))
+}
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+type circuitBreaker struct {
+	sync.Mutex
+	method string
+	policy *CircuitBreakerPolicy
+
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probes   int
+	round    uint64 // 进入半开状态的次数，用于区分探测请求属于哪一轮
+}
+
+func newCircuitBreaker(method string, policy *CircuitBreakerPolicy) *circuitBreaker {
+	cb := &circuitBreaker{
+		method: method,
+		policy: policy,
+	}
+	cb.setState(stateClosed)
+	return cb
+}
+
+// 调用方需持有锁
+func (cb *circuitBreaker) setState(state breakerState) {
+	cb.state = state
+	if gauge, _ := monitor.Monitor.CircuitBreakerState(cb.method); gauge != nil {
+		gauge.Set(float64(state))
+	}
+}
+
+// allow 返回是否允许请求通过，半开状态下放行的探测请求返回当前的轮次(非0)，
+// record时只有当前轮次的探测请求才能改变半开状态
+func (cb *circuitBreaker) allow() (probe uint64, ok bool) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	switch cb.state {
+	case stateOpen:
+		if time.Since(cb.openedAt) < cb.policy.OpenTimeout {
+			return 0, false
+		}
+		cb.setState(stateHalfOpen)
+		cb.probes = 0
+		cb.round++
+		fallthrough
+	case stateHalfOpen:
+		limit := cb.policy.HalfOpenRequests
+		if limit <= 0 {
+			limit = 1
+		}
+		if cb.probes >= limit {
+			return 0, false
+		}
+		cb.probes++
+		return cb.round, true
+	default:
+		return 0, true
+	}
+}
+
+// record 记录请求结果，probe为allow返回的值
+// 熔断前放行、熔断后才返回的请求以及之前轮次的探测请求不影响当前状态
+func (cb *circuitBreaker) record(probe uint64, failed bool) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	switch cb.state {
+	case stateHalfOpen:
+		if probe != cb.round {
+			return
+		}
+		cb.probes--
+		if failed {
+			cb.open()
+		} else {
+			cb.failures = 0
+			cb.setState(stateClosed)
+		}
+	case stateClosed:
+		if probe != 0 {
+			return
+		}
+		if !failed {
+			cb.failures = 0
+			return
+		}
+		cb.failures++
+		if cb.failures >= cb.policy.FailureThreshold {
+			cb.open()
+		}
+	}
+}
+
+func (cb *circuitBreaker) open() {
+	cb.failures = 0
+	cb.openedAt = time.Now()
+	cb.setState(stateOpen)
+}
+
+// UnaryClientCircuitBreaker 按FullMethod模式配置熔断策略，每个方法独立熔断
+// 熔断期间直接返回ErrCircuitOpen，不再请求下游
+func UnaryClientCircuitBreaker(policies map[string]*CircuitBreakerPolicy) grpc.UnaryClientInterceptor {
+	patterns := make([]string, 0, len(policies))
+	for p := range policies {
+		patterns = append(patterns, p)
+	}
+	matcher := newMethodMatcher(patterns)
+
+	var (
+		mu       sync.Mutex
+		breakers = make(map[string]*circuitBreaker)
+	)
+	getBreaker := func(method string, policy *CircuitBreakerPolicy) *circuitBreaker {
+		mu.Lock()
+		defer mu.Unlock()
+		cb, ok := breakers[method]
+		if !ok {
+			cb = newCircuitBreaker(method, policy)
+			breakers[method] = cb
+		}
+		return cb
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		pattern, ok := matcher.match(method)
+		if !ok || policies[pattern].FailureThreshold <= 0 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		policy := policies[pattern]
+
+		cb := getBreaker(method, policy)
+		probe, ok := cb.allow()
+		if !ok {
+			return ErrCircuitOpen
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		cb.record(probe, policy.isFailure(err))
+		return err
+	}
+}
@@ -0,0 +1,238 @@
+package grpcmiddleware
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	proto "umbrella-go/umbrella-common/proto"
+)
+
+const testMethod = "/test.Echo/Echo"
+
+func failingInvoker(calls *int, code codes.Code, failures int) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		if *calls <= failures {
+			return status.Error(code, "test")
+		}
+		return nil
+	}
+}
+
+func TestMatchMethodPattern(t *testing.T) {
+	assert := assert.New(t)
+
+	mm := newMethodMatcher([]string{"*", "/test.Echo/*", testMethod})
+	p, ok := mm.match(testMethod)
+	assert.True(ok)
+	assert.Equal(testMethod, p)
+
+	p, ok = mm.match("/test.Echo/EchoStream")
+	assert.True(ok)
+	assert.Equal("/test.Echo/*", p)
+
+	p, ok = mm.match("/other.Service/Call")
+	assert.True(ok)
+	assert.Equal("*", p)
+}
+
+func TestUnaryClientRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := UnaryClientRetry(map[string]*RetryPolicy{
+		"/test.Echo/*": {MaxAttempts: 3},
+	})
+
+	calls := 0
+	err := interceptor(context.Background(), testMethod, nil, nil, nil, failingInvoker(&calls, codes.Unavailable, 2))
+	assert.Nil(err)
+	assert.Equal(3, calls)
+
+	calls = 0
+	err = interceptor(context.Background(), testMethod, nil, nil, nil, failingInvoker(&calls, codes.InvalidArgument, 2))
+	assert.Equal(codes.InvalidArgument, grpc.Code(err))
+	assert.Equal(1, calls)
+}
+
+func TestUnaryClientRetryBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	var backoffs []int
+	interceptor := UnaryClientRetry(map[string]*RetryPolicy{
+		testMethod: {MaxAttempts: 3, Backoff: func(attempt int) time.Duration {
+			backoffs = append(backoffs, attempt)
+			return 0
+		}},
+	})
+
+	calls := 0
+	err := interceptor(context.Background(), testMethod, nil, nil, nil, failingInvoker(&calls, codes.Unavailable, 5))
+	assert.Equal(codes.Unavailable, grpc.Code(err))
+	assert.Equal(3, calls)
+	assert.Equal([]int{1, 2}, backoffs)
+
+	// 等待退避期间ctx被取消时立即返回上一次的错误
+	ctx, cancel := context.WithCancel(context.Background())
+	interceptor = UnaryClientRetry(map[string]*RetryPolicy{
+		testMethod: {MaxAttempts: 3, Backoff: func(attempt int) time.Duration {
+			cancel()
+			return time.Hour
+		}},
+	})
+	calls = 0
+	err = interceptor(ctx, testMethod, nil, nil, nil, failingInvoker(&calls, codes.Unavailable, 5))
+	assert.Equal(codes.Unavailable, grpc.Code(err))
+	assert.Equal(1, calls)
+}
+
+func TestUnaryClientHedging(t *testing.T) {
+	assert := assert.New(t)
+
+	// 第一次请求一直阻塞到被取消，Delay后发起的第二次请求胜出
+	interceptor := UnaryClientHedging(map[string]*HedgingPolicy{
+		testMethod: {MaxAttempts: 2, Delay: time.Millisecond},
+	})
+	canceled := make(chan struct{})
+	var calls int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			close(canceled)
+			return ctx.Err()
+		}
+		reply.(*proto.Error).Code = 2
+		return nil
+	}
+	reply := &proto.Error{}
+	assert.Nil(interceptor(context.Background(), testMethod, nil, reply, nil, invoker))
+	assert.Equal(int32(2), reply.Code)
+	<-canceled
+
+	// NonFatalCodes不等待Delay，立即发起下一次请求
+	interceptor = UnaryClientHedging(map[string]*HedgingPolicy{
+		testMethod: {MaxAttempts: 3, Delay: time.Hour, NonFatalCodes: []codes.Code{codes.Unavailable}},
+	})
+	n := 0
+	reply = &proto.Error{}
+	invoker = func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		n++
+		if n < 3 {
+			return status.Error(codes.Unavailable, "test")
+		}
+		reply.(*proto.Error).Code = int32(n)
+		return nil
+	}
+	assert.Nil(interceptor(context.Background(), testMethod, nil, reply, nil, invoker))
+	assert.Equal(int32(3), reply.Code)
+
+	// 其他错误直接返回
+	n = 0
+	invoker = func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		n++
+		return status.Error(codes.InvalidArgument, "test")
+	}
+	err := interceptor(context.Background(), testMethod, nil, &proto.Error{}, nil, invoker)
+	assert.Equal(codes.InvalidArgument, grpc.Code(err))
+	assert.Equal(1, n)
+}
+
+func TestUnaryClientCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+
+	interceptor := UnaryClientCircuitBreaker(map[string]*CircuitBreakerPolicy{
+		testMethod: {FailureThreshold: 2, OpenTimeout: 50 * time.Millisecond},
+	})
+
+	calls := 0
+	invoker := failingInvoker(&calls, codes.Unavailable, 2)
+	for i := 0; i < 2; i++ {
+		interceptor(context.Background(), testMethod, nil, nil, nil, invoker)
+	}
+
+	err := interceptor(context.Background(), testMethod, nil, nil, nil, invoker)
+	assert.Equal(ErrCircuitOpen, err)
+	assert.Equal(2, calls)
+}
+
+// allowed 断言allow放行并返回probe
+func allowed(t *testing.T, cb *circuitBreaker) uint64 {
+	probe, ok := cb.allow()
+	assert.True(t, ok)
+	return probe
+}
+
+func rejected(t *testing.T, cb *circuitBreaker) {
+	_, ok := cb.allow()
+	assert.False(t, ok)
+}
+
+// expireOpen 不等待OpenTimeout，直接把熔断开始的时间往前移
+func expireOpen(cb *circuitBreaker) {
+	cb.Lock()
+	cb.openedAt = cb.openedAt.Add(-time.Minute)
+	cb.Unlock()
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	assert := assert.New(t)
+
+	cb := newCircuitBreaker(testMethod, &CircuitBreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Minute})
+	cb.record(allowed(t, cb), true)
+	rejected(t, cb)
+
+	expireOpen(cb)
+	probe := allowed(t, cb)
+	assert.NotEqual(uint64(0), probe)
+	rejected(t, cb)
+	cb.record(probe, false)
+	assert.Equal(stateClosed, cb.state)
+	assert.Equal(uint64(0), allowed(t, cb))
+}
+
+func TestCircuitBreakerStaleCalls(t *testing.T) {
+	assert := assert.New(t)
+
+	cb := newCircuitBreaker(testMethod, &CircuitBreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Minute})
+
+	// 关闭状态下放行的请求在熔断、进入半开后才返回，不影响探测
+	slow := allowed(t, cb)
+	cb.record(allowed(t, cb), true)
+	assert.Equal(stateOpen, cb.state)
+	expireOpen(cb)
+	probe := allowed(t, cb)
+	cb.record(slow, false)
+	assert.Equal(stateHalfOpen, cb.state)
+	assert.Equal(1, cb.probes)
+	rejected(t, cb)
+
+	// 上一轮的探测请求在下一轮半开时才返回，同样被忽略
+	cb.record(probe, true)
+	assert.Equal(stateOpen, cb.state)
+	expireOpen(cb)
+	probe2 := allowed(t, cb)
+	cb.record(probe, false)
+	assert.Equal(stateHalfOpen, cb.state)
+	rejected(t, cb)
+
+	// 本轮探测失败后重新熔断
+	cb.record(probe2, true)
+	assert.Equal(stateOpen, cb.state)
+	rejected(t, cb)
+
+	// 探测成功关闭后，同一轮晚返回的失败探测不计入关闭状态的失败数
+	cb = newCircuitBreaker(testMethod, &CircuitBreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenRequests: 2})
+	cb.record(allowed(t, cb), true)
+	expireOpen(cb)
+	p1, p2 := allowed(t, cb), allowed(t, cb)
+	cb.record(p1, false)
+	assert.Equal(stateClosed, cb.state)
+	cb.record(p2, true)
+	assert.Equal(stateClosed, cb.state)
+}
@@ -0,0 +1,42 @@
+package grpcmiddleware
+
+import (
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+type ClientConfig struct {
+	// TLS 为空时使用明文连接，通常由tlsutil.Reloader.ClientConfig生成，配置了证书时使用mTLS
+	TLS *tls.Config
+
+	// 在请求ID和监控之后执行的拦截器，如重试、熔断
+	UnaryInterceptors  []grpc.UnaryClientInterceptor
+	StreamInterceptors []grpc.StreamClientInterceptor
+}
+
+// Dial 创建透传请求ID并记录调用监控的grpc.ClientConn，opts在默认选项之后生效
+func Dial(target string, config ClientConfig, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	unary := append([]grpc.UnaryClientInterceptor{
+		UnaryClientRequestID(),
+		monitor.MonitorClientInceptorUnary(),
+	}, config.UnaryInterceptors...)
+	stream := append([]grpc.StreamClientInterceptor{
+		StreamClientRequestID(),
+		monitor.MonitorClientInceptorStream(),
+	}, config.StreamInterceptors...)
+
+	dialOpts := []grpc.DialOption{
+		WithUnaryClientChain(unary...),
+		WithStreamClientChain(stream...),
+	}
+	if config.TLS != nil {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(config.TLS)))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithInsecure())
+	}
+	return grpc.Dial(target, append(dialOpts, opts...)...)
+}
@@ -20,19 +20,48 @@ func MakeUnaryServerErrorTranslator(errorMsgGetter ErrorMsgGetter) grpc.UnarySer
 		ctx = lang.ContextSetLanguages(ctx, languages)
 		resp, err = handler(ctx, req)
 		if resp, ok := resp.(errorGetter); ok {
-			err := resp.GetError()
-
-			if err != nil {
-				// 设置err的message信息
-				if err.Message == "" {
-					if msg := errorMsgGetter(int(err.Code), languages); msg != "" {
-						err.Message = msg
-					} else {
-						err.Message = "Unknown error"
-					}
-				}
-			}
+			translateError(resp.GetError(), languages, errorMsgGetter)
 		}
 		return resp, err
 	}
 }
+
+// 设置err的message信息
+func translateError(err *proto.Error, languages []string, errorMsgGetter ErrorMsgGetter) {
+	if err == nil || err.Message != "" {
+		return
+	}
+
+	if msg := errorMsgGetter(int(err.Code), languages); msg != "" {
+		err.Message = msg
+	} else {
+		err.Message = "Unknown error"
+	}
+}
+
+type translatedServerStream struct {
+	grpc.ServerStream
+	languages      []string
+	errorMsgGetter ErrorMsgGetter
+}
+
+func (s *translatedServerStream) SendMsg(m interface{}) error {
+	if m, ok := m.(errorGetter); ok {
+		translateError(m.GetError(), s.languages, s.errorMsgGetter)
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// MakeStreamServerErrorTranslator 在stream发送每条消息前翻译其中的错误信息
+func MakeStreamServerErrorTranslator(errorMsgGetter ErrorMsgGetter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		languages := lang.FromIncomingContext(ctx)
+		ctx = lang.ContextSetLanguages(ctx, languages)
+		return handler(srv, &translatedServerStream{
+			ServerStream:   ServerStreamWithContext(ss, ctx),
+			languages:      languages,
+			errorMsgGetter: errorMsgGetter,
+		})
+	}
+}
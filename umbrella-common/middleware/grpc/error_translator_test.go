@@ -0,0 +1,79 @@
+package grpcmiddleware
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"umbrella-go/umbrella-common/lang"
+	proto "umbrella-go/umbrella-common/proto"
+)
+
+// fakeServerStream 记录SendMsg收到的消息，sendErr非空时发送失败
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	sent    []interface{}
+	sendErr error
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return s.sendErr
+}
+
+func TestStreamServerErrorTranslator(t *testing.T) {
+	assert := assert.New(t)
+
+	var gotLanguages []string
+	interceptor := MakeStreamServerErrorTranslator(func(code int, languages []string) string {
+		gotLanguages = languages
+		if code == 10001 {
+			return "参数错误"
+		}
+		return ""
+	})
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Echo/Chat"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("language", "zh-CN"))
+
+	ss := &fakeServerStream{ctx: ctx}
+	handlerErr := status.Error(codes.NotFound, "not found")
+	err := interceptor(nil, ss, info, func(srv interface{}, stream grpc.ServerStream) error {
+		assert.Equal([]string{"zh-CN"}, lang.FromContext(stream.Context()))
+		assert.Nil(stream.SendMsg(errorResp{err: &proto.Error{Code: 10001}}))
+		assert.Nil(stream.SendMsg(errorResp{err: &proto.Error{Code: 10002}}))
+		assert.Nil(stream.SendMsg(errorResp{err: &proto.Error{Code: 10001, Message: "custom"}}))
+		assert.Nil(stream.SendMsg(errorResp{}))
+		return handlerErr
+	})
+	// handler返回的错误原样传出
+	assert.Equal(handlerErr, err)
+	assert.Equal([]string{"zh-CN", "en-US"}, gotLanguages)
+	if assert.Equal(4, len(ss.sent)) {
+		assert.Equal("参数错误", ss.sent[0].(errorResp).err.Message)
+		assert.Equal("Unknown error", ss.sent[1].(errorResp).err.Message)
+		assert.Equal("custom", ss.sent[2].(errorResp).err.Message)
+		assert.Nil(ss.sent[3].(errorResp).err)
+	}
+
+	// 底层SendMsg的错误原样返回，消息仍会先被翻译
+	sendErr := errors.New("send failed")
+	ss = &fakeServerStream{ctx: ctx, sendErr: sendErr}
+	err = interceptor(nil, ss, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return stream.SendMsg(errorResp{err: &proto.Error{Code: 10001}})
+	})
+	assert.Equal(sendErr, err)
+	if assert.Equal(1, len(ss.sent)) {
+		assert.Equal("参数错误", ss.sent[0].(errorResp).err.Message)
+	}
+}
@@ -0,0 +1,106 @@
+package grpcmiddleware
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+// HedgingPolicy 对冲请求策略，只应用于幂等的读接口
+// 首次请求发出后每隔Delay仍未返回就再发起一次，直到MaxAttempts，最先成功的结果胜出
+type HedgingPolicy struct {
+	MaxAttempts   int
+	Delay         time.Duration
+	NonFatalCodes []codes.Code // 返回这些状态码时立即发起下一次请求，而不是直接失败
+}
+
+type hedgeResult struct {
+	reply proto.Message
+	err   error
+}
+
+// UnaryClientHedging 按FullMethod模式配置对冲策略，reply必须是proto.Message
+func UnaryClientHedging(policies map[string]*HedgingPolicy) grpc.UnaryClientInterceptor {
+	patterns := make([]string, 0, len(policies))
+	for p := range policies {
+		patterns = append(patterns, p)
+	}
+	matcher := newMethodMatcher(patterns)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		pattern, ok := matcher.match(method)
+		msg, isProto := reply.(proto.Message)
+		if !ok || !isProto || policies[pattern].MaxAttempts <= 1 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		policy := policies[pattern]
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// 每次请求使用独立的reply，避免并发写入
+		results := make(chan hedgeResult, policy.MaxAttempts)
+		launch := func() {
+			r := proto.Clone(msg)
+			r.Reset()
+			go func() {
+				err := invoker(ctx, method, req, r, cc, opts...)
+				results <- hedgeResult{reply: r, err: err}
+			}()
+		}
+
+		launch()
+		launched := 1
+		timer := time.NewTimer(policy.Delay)
+		defer timer.Stop()
+
+		var lastErr error
+		for received := 0; received < launched; {
+			select {
+			case <-timer.C:
+				if launched < policy.MaxAttempts {
+					launch()
+					launched++
+					observeHedge(method)
+					timer.Reset(policy.Delay)
+				}
+			case res := <-results:
+				received++
+				if res.err == nil {
+					msg.Reset()
+					proto.Merge(msg, res.reply)
+					return nil
+				}
+				if !containsCode(policy.NonFatalCodes, grpc.Code(res.err)) {
+					return res.err
+				}
+
+				lastErr = res.err
+				if launched < policy.MaxAttempts && ctx.Err() == nil {
+					if !timer.Stop() {
+						select {
+						case <-timer.C:
+						default:
+						}
+					}
+					launch()
+					launched++
+					observeHedge(method)
+					timer.Reset(policy.Delay)
+				}
+			}
+		}
+		return lastErr
+	}
+}
+
+func observeHedge(method string) {
+	if counter, _ := monitor.Monitor.HedgeCounter(method); counter != nil {
+		counter.Inc()
+	}
+}
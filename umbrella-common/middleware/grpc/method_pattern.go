@@ -0,0 +1,45 @@
+package grpcmiddleware
+
+import (
+	"sort"
+	"strings"
+)
+
+// 按FullMethod匹配配置项，支持三种模式：
+//
+//	精确匹配   "/pkg.Service/Method"
+//	服务级匹配 "/pkg.Service/*"
+//	全局匹配   "*"
+//
+// 多个模式同时命中时，越具体(越长)的模式优先
+type methodMatcher struct {
+	patterns []string
+}
+
+func newMethodMatcher(patterns []string) *methodMatcher {
+	ps := make([]string, len(patterns))
+	copy(ps, patterns)
+	sort.SliceStable(ps, func(i, j int) bool {
+		return len(ps[i]) > len(ps[j])
+	})
+	return &methodMatcher{patterns: ps}
+}
+
+func (mm *methodMatcher) match(fullMethod string) (string, bool) {
+	for _, p := range mm.patterns {
+		if matchMethodPattern(p, fullMethod) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+func matchMethodPattern(pattern, fullMethod string) bool {
+	if pattern == "*" || pattern == fullMethod {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(fullMethod, pattern[:len(pattern)-1])
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package grpcmiddleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"umbrella-go/umbrella-common/errors"
+	"umbrella-go/umbrella-common/lang"
+	"umbrella-go/umbrella-common/log"
+	"umbrella-go/umbrella-common/monitor"
+)
+
+// 将panic转换为codes.Internal错误，错误信息按调用方的语言翻译
+func recoverToError(ctx context.Context, fullMethod string, p interface{}, errorMsgGetter ErrorMsgGetter) error {
+	log.Error(ctx, "grpc panic recovered",
+		log.String("method", fullMethod), log.String("panic", fmt.Sprint(p)), log.String("stack", string(debug.Stack())))
+
+	if counter, _ := monitor.Monitor.PanicCounter(fullMethod); counter != nil {
+		counter.Inc()
+	}
+
+	msg := ""
+	if errorMsgGetter != nil {
+		msg = errorMsgGetter(errors.InternalErrorCode, lang.FromIncomingContext(ctx))
+	}
+	if msg == "" {
+		// panic的内容只记录在日志中，不返回给调用方
+		msg = "internal error"
+	}
+	return status.Error(codes.Internal, msg)
+}
+
+// UnaryServerRecovery 捕获handler中的panic，避免整个gRPC服务进程退出
+func UnaryServerRecovery(errorMsgGetter ErrorMsgGetter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				resp, err = nil, recoverToError(ctx, info.FullMethod, p, errorMsgGetter)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerRecovery(errorMsgGetter ErrorMsgGetter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recoverToError(ss.Context(), info.FullMethod, p, errorMsgGetter)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
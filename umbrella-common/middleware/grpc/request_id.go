@@ -0,0 +1,67 @@
+package grpcmiddleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"umbrella-go/umbrella-common/log"
+)
+
+const requestIDKey = "x-request-id"
+
+func contextWithRequestID(ctx context.Context) context.Context {
+	id := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vs := md[requestIDKey]; len(vs) > 0 {
+			id = vs[0]
+		}
+	}
+	if id == "" {
+		b := make([]byte, 16)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	return log.ContextWithRequestID(ctx, id)
+}
+
+// UnaryServerRequestID 从metadata中取请求ID，没有时生成一个新的，写入Context供日志使用
+func UnaryServerRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(contextWithRequestID(ctx), req)
+	}
+}
+
+func StreamServerRequestID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, ServerStreamWithContext(ss, contextWithRequestID(ss.Context())))
+	}
+}
+
+func injectRequestID(ctx context.Context) context.Context {
+	id := log.RequestIDFromContext(ctx)
+	if id == "" {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if len(md[requestIDKey]) > 0 {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, metadata.Join(md, metadata.Pairs(requestIDKey, id)))
+}
+
+// UnaryClientRequestID 将Context中的请求ID透传给下游服务
+func UnaryClientRequestID() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(injectRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+func StreamClientRequestID() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(injectRequestID(ctx), desc, cc, method, opts...)
+	}
+}
@@ -0,0 +1,104 @@
+package grpcmiddleware
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+var defaultRetryCodes = []codes.Code{codes.Unavailable}
+
+// BackoffFunc 返回第attempt次重试(从1开始)前需要等待的时长
+type BackoffFunc func(attempt int) time.Duration
+
+// ExponentialBackoff 指数退避，等待时长为base*2^(attempt-1)，不超过max，
+// jitter为[0, 1]之间的随机抖动比例
+func ExponentialBackoff(base, max time.Duration, jitter float64) BackoffFunc {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		if jitter > 0 {
+			delta := float64(d) * jitter
+			d = time.Duration(float64(d) - delta + rand.Float64()*2*delta)
+		}
+		return d
+	}
+}
+
+type RetryPolicy struct {
+	MaxAttempts int          // 包含首次调用在内的最大尝试次数
+	Codes       []codes.Code // 需要重试的状态码，为空时只重试Unavailable
+	Backoff     BackoffFunc  // 为空时不等待直接重试
+}
+
+func (p *RetryPolicy) retryable(code codes.Code) bool {
+	cs := p.Codes
+	if len(cs) == 0 {
+		cs = defaultRetryCodes
+	}
+	return containsCode(cs, code)
+}
+
+// UnaryClientRetry 按FullMethod模式配置重试策略，未匹配到策略的方法不做重试
+func UnaryClientRetry(policies map[string]*RetryPolicy) grpc.UnaryClientInterceptor {
+	patterns := make([]string, 0, len(policies))
+	for p := range policies {
+		patterns = append(patterns, p)
+	}
+	matcher := newMethodMatcher(patterns)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		pattern, ok := matcher.match(method)
+		if !ok || policies[pattern].MaxAttempts <= 1 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		policy := policies[pattern]
+
+		var err error
+		for attempt := 1; ; attempt++ {
+			err = invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || attempt >= policy.MaxAttempts {
+				return err
+			}
+
+			code := grpc.Code(err)
+			if !policy.retryable(code) || ctx.Err() != nil {
+				return err
+			}
+
+			if counter, _ := monitor.Monitor.RetryCounter(method, strconv.Itoa(int(code))); counter != nil {
+				counter.Inc()
+			}
+
+			if policy.Backoff != nil {
+				timer := time.NewTimer(policy.Backoff(attempt))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return err
+				case <-timer.C:
+				}
+			}
+		}
+	}
+}
+
+func containsCode(cs []codes.Code, code codes.Code) bool {
+	for _, c := range cs {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"time"
+
+	"umbrella-go/umbrella-common/log"
+	"umbrella-go/umbrella-common/monitor"
+)
+
+// AccessLogConfig 与gRPC访问日志共用配置
+type AccessLogConfig = log.AccessLogConfig
+
+// AccessLog 每个请求输出一行访问日志，出错(状态码>=400或有业务错误码)和慢请求总是记录，
+// 成功的请求按SuccessSampleRate采样
+// 需要通过router.Use(AccessLog(config).Wrap)放在chi路由内部才能取到路由模板
+func AccessLog(config AccessLogConfig) ServerMiddleware {
+	logger := config.AccessLogger()
+
+	return func(rw http.ResponseWriter, req *http.Request, next http.Handler) {
+		rr := monitor.ResponseRecorderFromContext(req.Context())
+		if rr == nil {
+			rr = monitor.NewResponseRecorder(rw)
+			req = req.WithContext(monitor.ContextWithResponseRecorder(req.Context(), rr))
+		}
+
+		start := time.Now()
+		defer func() {
+			cost := time.Now().Sub(start)
+			status := rr.Status()
+			// handler panic时还未写入响应，外层的Recoverer会返回500，这里按500记录后继续panic
+			if p := recover(); p != nil {
+				status = http.StatusInternalServerError
+				defer panic(p)
+			}
+
+			level, slow, ok := config.AccessLevel(status >= http.StatusInternalServerError,
+				status >= http.StatusBadRequest || rr.ErrorCode() != 0, cost)
+			if !ok {
+				return
+			}
+
+			logger.Log(req.Context(), level, "http access",
+				log.String("method", req.Method),
+				log.String("route", monitor.RoutePattern(req)),
+				log.String("path", req.URL.Path),
+				log.Int("status", status),
+				log.Int("error_code", rr.ErrorCode()),
+				log.Duration("latency", cost),
+				log.Bool("slow", slow),
+				log.Int64("bytes_in", req.ContentLength),
+				log.Int64("bytes_out", rr.BytesWritten()),
+				log.String("remote", req.RemoteAddr),
+			)
+		}()
+
+		next.ServeHTTP(rr, req)
+	}
+}
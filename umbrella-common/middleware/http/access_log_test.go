@@ -0,0 +1,145 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+
+	"umbrella-go/umbrella-common/json"
+	"umbrella-go/umbrella-common/log"
+	"umbrella-go/umbrella-common/monitor"
+)
+
+// newTestAccessLogger 返回输出到临时文件的Logger，调用方负责删除文件
+func newTestAccessLogger(t *testing.T) (*log.Logger, string) {
+	f, err := ioutil.TempFile("", "access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	logger, err := log.New(log.Config{Output: f.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logger, f.Name()
+}
+
+func readAccessLogs(t *testing.T, name string) []map[string]interface{} {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAccessLog(t *testing.T) {
+	assert := assert.New(t)
+
+	logger, name := newTestAccessLogger(t)
+	defer os.Remove(name)
+	r := chi.NewRouter()
+	// 采样率极低，成功的请求不记录
+	r.Use(AccessLog(AccessLogConfig{Logger: logger, SuccessSampleRate: 1e-9, SlowThreshold: 20 * time.Millisecond}).Wrap)
+	r.Get("/ok/{id}", func(w http.ResponseWriter, req *http.Request) {})
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+	})
+	r.Get("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+		monitor.SetRespCode(req.Context(), 10001)
+	})
+	r.Get("/panic", func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	})
+
+	for _, path := range []string{"/ok/1", "/slow", "/users/1"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	assert.Panics(func() {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	})
+
+	logs := readAccessLogs(t, name)
+	if !assert.Equal(3, len(logs)) {
+		return
+	}
+
+	assert.Equal("/slow", logs[0]["route"])
+	assert.Equal("warn", logs[0]["level"])
+	assert.Equal(true, logs[0]["slow"])
+
+	assert.Equal("/users/{id}", logs[1]["route"])
+	assert.Equal("/users/1", logs[1]["path"])
+	assert.Equal("warn", logs[1]["level"])
+	assert.Equal(float64(10001), logs[1]["error_code"])
+	assert.Equal(float64(http.StatusOK), logs[1]["status"])
+
+	// panic时记录为500
+	assert.Equal("/panic", logs[2]["route"])
+	assert.Equal("error", logs[2]["level"])
+	assert.Equal(float64(http.StatusInternalServerError), logs[2]["status"])
+}
+
+func TestDefaultServerChainRecoversPanic(t *testing.T) {
+	assert := assert.New(t)
+
+	logger, name := newTestAccessLogger(t)
+	defer os.Remove(name)
+	r := chi.NewRouter()
+	r.Use(monitor.HttpMonitor, AccessLog(AccessLogConfig{Logger: logger}).Wrap)
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	getter := func(code int, languages []string) string {
+		if len(languages) > 0 && languages[0] == "zh-CN" {
+			return "服务内部错误"
+		}
+		return "internal error"
+	}
+	chain, err := DefaultServerChain(ServerChainConfig{Timeout: time.Second, ErrorMsgGetter: getter})
+	assert.Nil(err)
+	h := chain.Wrap(r)
+
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set("Accept-Language", "zh-CN")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.Equal(http.StatusInternalServerError, w.Code)
+	var body map[string]interface{}
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(float64(500), body["code"])
+	assert.Equal("服务内部错误", body["message"])
+
+	entries := readAccessLogs(t, name)
+	if assert.Equal(1, len(entries)) {
+		assert.Equal(float64(http.StatusInternalServerError), entries[0]["status"])
+	}
+
+	// 没有ErrorMsgGetter时同样返回500
+	chain, err = DefaultServerChain(ServerChainConfig{})
+	assert.Nil(err)
+	w = httptest.NewRecorder()
+	chain.Wrap(r).ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	assert.Equal(http.StatusInternalServerError, w.Code)
+	assert.Contains(w.Body.String(), "internal error")
+}
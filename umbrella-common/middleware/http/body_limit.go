@@ -0,0 +1,25 @@
+package httpmiddleware
+
+import (
+	"net/http"
+)
+
+// BodyLimit 限制请求Body的大小，Content-Length超出时直接返回413，
+// 未声明长度的请求在读取超出maxBytes时返回错误
+func BodyLimit(maxBytes int64) ServerMiddleware {
+	if maxBytes <= 0 {
+		return nil
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request, next http.Handler) {
+		if req.ContentLength > maxBytes {
+			http.Error(rw, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if req.Body != nil {
+			req.Body = http.MaxBytesReader(rw, req.Body, maxBytes)
+		}
+		next.ServeHTTP(rw, req)
+	}
+}
@@ -0,0 +1,45 @@
+package httpmiddleware
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+type ClientConfig struct {
+	Target  string        // 下游服务名称，不为空时记录调用监控
+	Timeout time.Duration // 整个请求的超时时间，为0时不限制
+	// TLS 不为空时用于https请求，通常由tlsutil.Reloader.ClientConfig生成，配置了证书时使用mTLS
+	TLS         *tls.Config
+	Middlewares []ClientMiddleware // 在请求ID和监控之后执行
+}
+
+// NewClient 创建注入请求ID并记录调用监控的http.Client，每次调用都会新建连接池，应复用返回的Client
+func NewClient(config ClientConfig) *http.Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       config.TLS,
+		// 自定义TLSClientConfig时需要显式开启HTTP/2
+		ForceAttemptHTTP2: true,
+	}
+
+	middlewares := []ClientMiddleware{InjectRequestID()}
+	if config.Target != "" {
+		middlewares = append(middlewares, MonitorClient(config.Target))
+	}
+	middlewares = append(middlewares, config.Middlewares...)
+
+	return &http.Client{
+		Transport: WithClientMiddleware(transport, middlewares...),
+		Timeout:   config.Timeout,
+	}
+}
@@ -0,0 +1,47 @@
+package httpmiddleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+type routeTemplateKey struct{}
+
+// ContextWithRouteTemplate 设置请求的路由模板(如"/users/{id}")，用作监控中的api标签
+// 直接使用URL Path会导致监控指标的标签数量不可控
+func ContextWithRouteTemplate(ctx context.Context, template string) context.Context {
+	return context.WithValue(ctx, routeTemplateKey{}, template)
+}
+
+func RouteTemplateFromContext(ctx context.Context) string {
+	template, ok := ctx.Value(routeTemplateKey{}).(string)
+	if !ok {
+		return ""
+	}
+	return template
+}
+
+// MonitorClient 记录对下游服务target的HTTP调用，code为HTTP状态码，请求失败时为"error"
+func MonitorClient(target string) ClientMiddleware {
+	return func(req *http.Request, next http.RoundTripper) (*http.Response, error) {
+		template := RouteTemplateFromContext(req.Context())
+		if template == "" {
+			template = "unknown"
+		}
+		api := req.Method + " " + template
+
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+		monitor.ObserveClient(target, api, code, time.Now().Sub(start))
+
+		return resp, err
+	}
+}
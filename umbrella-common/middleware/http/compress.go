@@ -0,0 +1,139 @@
+package httpmiddleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	encodingGzip   = "gzip"
+	encodingBrotli = "br"
+)
+
+// 按客户端的Accept-Encoding选择压缩算法，优先使用brotli
+func negotiateEncoding(acceptEncoding string) string {
+	var gzipOK, brotliOK bool
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		item = strings.TrimSpace(item)
+		parts := strings.SplitN(item, ";", 2)
+		if len(parts) == 2 && strings.Replace(strings.TrimSpace(parts[1]), " ", "", -1) == "q=0" {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case encodingBrotli:
+			brotliOK = true
+		case encodingGzip:
+			gzipOK = true
+		}
+	}
+
+	switch {
+	case brotliOK:
+		return encodingBrotli
+	case gzipOK:
+		return encodingGzip
+	default:
+		return ""
+	}
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	level    int
+
+	writer      io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	header := cw.Header()
+	// 已经编码过的响应或没有Body的响应不再压缩
+	if header.Get("Content-Encoding") == "" && code != http.StatusNoContent && code != http.StatusNotModified {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		switch cw.encoding {
+		case encodingBrotli:
+			cw.writer = brotli.NewWriterLevel(cw.ResponseWriter, brotli.DefaultCompression)
+		case encodingGzip:
+			gw, err := gzip.NewWriterLevel(cw.ResponseWriter, cw.level)
+			if err != nil {
+				gw = gzip.NewWriter(cw.ResponseWriter)
+			}
+			cw.writer = gw
+		}
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.writer != nil {
+		return cw.writer.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *compressWriter) Flush() {
+	if f, ok := cw.writer.(interface {
+		Flush() error
+	}); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("http.Hijacker is not implemented")
+}
+
+func (cw *compressWriter) Close() error {
+	if cw.writer != nil {
+		return cw.writer.Close()
+	}
+	return nil
+}
+
+// Compress 根据Accept-Encoding使用brotli或gzip压缩响应，level为gzip的压缩级别
+func Compress(level int) ServerMiddleware {
+	return func(rw http.ResponseWriter, req *http.Request, next http.Handler) {
+		rw.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"))
+		if encoding == "" || req.Method == http.MethodHead {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: rw,
+			encoding:       encoding,
+			level:          level,
+		}
+		defer cw.Close()
+
+		next.ServeHTTP(cw, req)
+	}
+}
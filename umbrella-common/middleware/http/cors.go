@@ -0,0 +1,95 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	defaultCORSHeaders = []string{"Accept", "Accept-Language", "Content-Type", "Authorization", "Caller-Name"}
+)
+
+type CORSConfig struct {
+	AllowedOrigins []string // 支持"*"以及"https://*.example.com"形式的通配
+	AllowedMethods []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	// AllowCredentials 只对明确配置的origin生效，通过"*"匹配的origin不允许携带凭证
+	AllowCredentials bool
+	MaxAge           int // 预检请求结果的缓存时长，单位秒
+}
+
+// allowOrigin anyOrigin表示只通过"*"匹配，此时不能允许携带凭证，否则任意网站都可以带着用户的cookie访问
+func (c *CORSConfig) allowOrigin(origin string) (allowed bool, anyOrigin bool) {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			anyOrigin = true
+			continue
+		}
+		if strings.EqualFold(o, origin) {
+			return true, false
+		}
+		if i := strings.Index(o, "*"); i >= 0 {
+			prefix, suffix := o[:i], o[i+1:]
+			if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true, false
+			}
+		}
+	}
+	return anyOrigin, anyOrigin
+}
+
+// CORS 处理跨域请求，预检请求(OPTIONS)直接返回204，不再调用后续handler
+func CORS(config CORSConfig) ServerMiddleware {
+	methods := config.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	headers := config.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+	exposeHeaders := strings.Join(config.ExposedHeaders, ", ")
+
+	return func(rw http.ResponseWriter, req *http.Request, next http.Handler) {
+		origin := req.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		header := rw.Header()
+		header.Add("Vary", "Origin")
+		allowed, anyOrigin := config.allowOrigin(origin)
+		if !allowed {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		header.Set("Access-Control-Allow-Origin", origin)
+		if config.AllowCredentials && !anyOrigin {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", allowMethods)
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+			if config.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+			}
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if exposeHeaders != "" {
+			header.Set("Access-Control-Expose-Headers", exposeHeaders)
+		}
+		next.ServeHTTP(rw, req)
+	}
+}
@@ -0,0 +1,61 @@
+package httpmiddleware
+
+import (
+	"compress/gzip"
+	"time"
+
+	"umbrella-go/umbrella-common/render"
+)
+
+type ServerChainConfig struct {
+	TrustedProxies   []string
+	CORS             *CORSConfig // 为空时不处理跨域
+	MaxBodyBytes     int64       // 小于等于0时不限制
+	Timeout          time.Duration
+	DisableCompress  bool
+	CompressLevel    int // gzip压缩级别，为0时使用gzip.DefaultCompression
+	DisableRequestID bool
+	// ErrorMsgGetter 按调用方语言获取panic恢复后返回的错误信息，为空时返回"internal error"
+	ErrorMsgGetter render.ErrorMsgGetter
+}
+
+// DefaultServerChain 按以下顺序组合标准中间件：
+// Recoverer -> DenyInternal -> RequestID -> RealIP -> CORS -> BodyLimit -> Compress -> Timeout
+// Recoverer在最外层，内层的监控和访问日志记录500后继续panic，由Recoverer返回渲染后的500，
+// DenyInternal随后执行，对外的端口不提供/internal和pprof接口(健康检查和ping除外)，
+// RequestID随后执行以便所有日志都带上请求ID，RealIP再随后执行以便后续中间件拿到真实IP，
+// CORS预检请求不受Body限制和超时影响，
+// Compress在Timeout外层，保证超时返回的响应也能正确结束压缩流
+// TrustedProxies中有无效的CIDR或IP时返回错误
+func DefaultServerChain(config ServerChainConfig) (ServerMiddleware, error) {
+	middlewares := []ServerMiddleware{Recoverer(config.ErrorMsgGetter), DenyInternal()}
+
+	if !config.DisableRequestID {
+		middlewares = append(middlewares, RequestID())
+	}
+	if len(config.TrustedProxies) > 0 {
+		realIP, err := RealIP(config.TrustedProxies)
+		if err != nil {
+			return nil, err
+		}
+		middlewares = append(middlewares, realIP)
+	}
+	if config.CORS != nil {
+		middlewares = append(middlewares, CORS(*config.CORS))
+	}
+	if m := BodyLimit(config.MaxBodyBytes); m != nil {
+		middlewares = append(middlewares, m)
+	}
+	if !config.DisableCompress {
+		level := config.CompressLevel
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		middlewares = append(middlewares, Compress(level))
+	}
+	if m := Timeout(config.Timeout); m != nil {
+		middlewares = append(middlewares, m)
+	}
+
+	return ChainServerMiddlewares(middlewares...), nil
+}
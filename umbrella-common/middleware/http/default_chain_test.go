@@ -0,0 +1,181 @@
+package httpmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	assert := assert.New(t)
+
+	called := false
+	h := CORS(CORSConfig{AllowedOrigins: []string{"https://*.example.com"}}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest("OPTIONS", "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.False(called)
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal("https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	assert.True(called)
+	assert.Equal("", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSWildcardWithoutCredentials(t *testing.T) {
+	assert := assert.New(t)
+
+	h := CORS(CORSConfig{AllowedOrigins: []string{"*", "https://app.example.com"}, AllowCredentials: true}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal("https://evil.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal("", w.Header().Get("Access-Control-Allow-Credentials"))
+
+	req.Header.Set("Origin", "https://app.example.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal("true", w.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestRouteTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	wait := func(d time.Duration) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(d):
+				w.Write([]byte("ok"))
+			}
+		}
+	}
+	r := chi.NewRouter()
+	r.Get("/default", wait(time.Second))
+	r.With(RouteTimeout(time.Second)).Get("/longer", wait(100*time.Millisecond))
+	r.With(RouteTimeout(0)).Get("/unlimited", wait(100*time.Millisecond))
+	h := Timeout(20 * time.Millisecond).Wrap(r)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/default", nil))
+	assert.Equal(http.StatusGatewayTimeout, w.Code)
+
+	for _, path := range []string{"/longer", "/unlimited"} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal("ok", w.Body.String())
+	}
+
+	// 没有默认超时时单独生效
+	w = httptest.NewRecorder()
+	r.With(RouteTimeout(20*time.Millisecond)).Get("/shorter", wait(time.Second))
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/shorter", nil))
+	assert.Equal(http.StatusGatewayTimeout, w.Code)
+}
+
+func TestTimeoutDerivedContext(t *testing.T) {
+	assert := assert.New(t)
+
+	// handler派生的context超时后也应返回DeadlineExceeded，而不是Canceled
+	derivedErr := func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		<-ctx.Done()
+		w.Write([]byte(ctx.Err().Error()))
+	}
+	r := chi.NewRouter()
+	r.Get("/default", derivedErr)
+	r.With(RouteTimeout(50*time.Millisecond)).Get("/route", func(w http.ResponseWriter, r *http.Request) {
+		// 路由的超时替换默认超时，chi写入的路由信息仍然可见
+		assert.Equal("/route", chi.RouteContext(r.Context()).RoutePattern())
+		derivedErr(w, r)
+	})
+	h := Timeout(20 * time.Millisecond).Wrap(r)
+
+	for _, path := range []string{"/default", "/route"} {
+		w := httptest.NewRecorder()
+		start := time.Now()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(context.DeadlineExceeded.Error(), w.Body.String(), path)
+		if path == "/route" {
+			assert.True(time.Since(start) >= 50*time.Millisecond)
+		}
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	assert := assert.New(t)
+
+	var remoteAddr string
+	realIP, err := RealIP([]string{"10.0.0.0/8"})
+	assert.Nil(err)
+	h := realIP.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2, 10.0.0.2")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal("2.2.2.2", remoteAddr)
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "3.3.3.3:1234"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal("3.3.3.3:1234", remoteAddr)
+
+	_, err = RealIP([]string{"10.0.0.0/33"})
+	assert.NotNil(err)
+	_, err = DefaultServerChain(ServerChainConfig{TrustedProxies: []string{"not-an-ip"}})
+	assert.NotNil(err)
+}
+
+func TestBodyLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	h := BodyLimit(4).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("too large")))
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestDenyInternal(t *testing.T) {
+	assert := assert.New(t)
+
+	h := DenyInternal().Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("public"))
+	}))
+
+	for path, code := range map[string]int{
+		"/internal/metrics":  http.StatusNotFound,
+		"/debug/pprof/trace": http.StatusNotFound,
+		"/internal/ping":     http.StatusOK,
+		"/users":             http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(code, w.Code, path)
+	}
+}
@@ -0,0 +1,29 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"strings"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+// internalPrefixes 只在monitor的内部监听地址上提供的接口
+var internalPrefixes = []string{"/internal/", "/debug/pprof"}
+
+// DenyInternal 对外的服务拒绝访问内部接口，即使这些接口被误注册到对外的路由上
+// 健康检查和ping(monitor.ProbePaths)例外，直接由monitor注册的Handler处理，供负载均衡探测对外端口
+func DenyInternal() ServerMiddleware {
+	return func(rw http.ResponseWriter, req *http.Request, next http.Handler) {
+		if h := monitor.ProbeHandler(req.URL.Path); h != nil {
+			h.ServeHTTP(rw, req)
+			return
+		}
+		for _, prefix := range internalPrefixes {
+			if strings.HasPrefix(req.URL.Path, prefix) {
+				http.NotFound(rw, req)
+				return
+			}
+		}
+		next.ServeHTTP(rw, req)
+	}
+}
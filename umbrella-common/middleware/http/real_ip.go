@@ -0,0 +1,86 @@
+package httpmiddleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			if strings.Contains(p, ":") {
+				p += "/128"
+			} else {
+				p += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", p, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func isTrusted(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteIP(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// RealIP 只有请求来自受信任的代理(CIDR或IP)时，才从X-Forwarded-For/X-Real-IP中取客户端IP，
+// X-Forwarded-For从右向左跳过受信任的代理，取第一个不受信任的地址，结果写入req.RemoteAddr
+// trustedProxies中有无效的CIDR或IP时返回错误
+func RealIP(trustedProxies []string) (ServerMiddleware, error) {
+	nets, err := parseTrustedProxies(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request, next http.Handler) {
+		if !isTrusted(nets, remoteIP(req.RemoteAddr)) {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		var realIP string
+		if xff := req.Header.Get("X-Forwarded-For"); xff != "" {
+			ips := strings.Split(xff, ",")
+			for i := len(ips) - 1; i >= 0; i-- {
+				ip := net.ParseIP(strings.TrimSpace(ips[i]))
+				if ip == nil {
+					break
+				}
+				realIP = ip.String()
+				if !isTrusted(nets, ip) {
+					break
+				}
+			}
+		} else if xrip := net.ParseIP(strings.TrimSpace(req.Header.Get("X-Real-IP"))); xrip != nil {
+			realIP = xrip.String()
+		}
+
+		if realIP != "" {
+			req.RemoteAddr = realIP
+		}
+		next.ServeHTTP(rw, req)
+	}, nil
+}
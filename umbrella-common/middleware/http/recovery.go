@@ -0,0 +1,52 @@
+package httpmiddleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	chiRender "github.com/go-chi/render"
+
+	"umbrella-go/umbrella-common/errors"
+	"umbrella-go/umbrella-common/lang"
+	"umbrella-go/umbrella-common/log"
+	"umbrella-go/umbrella-common/monitor"
+	"umbrella-go/umbrella-common/render"
+)
+
+// Recoverer 捕获handler中的panic，记录调用栈和请求信息，
+// 并返回按调用方语言渲染的errors.InternalErrorCode错误，调用方的语言直接从请求头中读取
+// DefaultServerChain中位于最外层，errorMsgGetter为空时错误信息为"internal error"
+func Recoverer(errorMsgGetter render.ErrorMsgGetter) ServerMiddleware {
+	if errorMsgGetter == nil {
+		errorMsgGetter = func(code int, languages []string) string { return "internal error" }
+	}
+	renderJSON := render.MakeJSON(errorMsgGetter)
+
+	return func(rw http.ResponseWriter, req *http.Request, next http.Handler) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// http.ErrAbortHandler用于主动中断响应，交给net/http处理
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			log.Error(req.Context(), "http panic recovered",
+				log.String("method", req.Method), log.String("url", req.URL.String()), log.String("remote", req.RemoteAddr),
+				log.String("panic", fmt.Sprint(p)), log.String("stack", string(debug.Stack())))
+
+			if counter, _ := monitor.Monitor.PanicCounter(monitor.RoutePattern(req)); counter != nil {
+				counter.Inc()
+			}
+
+			req = req.WithContext(lang.ContextSetLanguages(req.Context(), lang.FromHttpHeader(req.Header)))
+			chiRender.Status(req, http.StatusInternalServerError)
+			renderJSON(rw, req, errors.NewInternalError("panic recovered"))
+		}()
+
+		next.ServeHTTP(rw, req)
+	}
+}
@@ -0,0 +1,49 @@
+package httpmiddleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"umbrella-go/umbrella-common/log"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+// RequestID 从X-Request-Id中取请求ID，没有时生成一个新的，
+// 写入Context供日志使用，并通过响应头返回给调用方
+func RequestID() ServerMiddleware {
+	return func(rw http.ResponseWriter, req *http.Request, next http.Handler) {
+		id := req.Header.Get(requestIDHeader)
+		if id == "" {
+			id = NewRequestID()
+		}
+		rw.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(rw, req.WithContext(log.ContextWithRequestID(req.Context(), id)))
+	}
+}
+
+// InjectRequestID 将Context中的请求ID透传给下游服务
+func InjectRequestID() ClientMiddleware {
+	return func(req *http.Request, next http.RoundTripper) (*http.Response, error) {
+		id := log.RequestIDFromContext(req.Context())
+		if id == "" || req.Header.Get(requestIDHeader) != "" {
+			return next.RoundTrip(req)
+		}
+
+		newReq := new(http.Request)
+		*newReq = *req
+		newReq.Header = make(http.Header, len(req.Header)+1)
+		for k, s := range req.Header {
+			newReq.Header[k] = s
+		}
+		newReq.Header.Set(requestIDHeader, id)
+		return next.RoundTrip(newReq)
+	}
+}
+
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,108 @@
+package httpmiddleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type timeoutWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.wroteHeader = true
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.wroteHeader = true
+	return tw.ResponseWriter.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+type timeoutKey struct{}
+
+// timeoutState 记录Timeout开始时的context，RouteTimeout以它为起点重新设置超时
+type timeoutState struct {
+	parent context.Context
+	start  time.Time
+	ctx    context.Context // handler最终使用的context
+}
+
+// valuesContext 取消和截止时间来自Context，Value来自values，
+// RouteTimeout用它保留Timeout之后的中间件(如chi路由)写入context的值
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+// withTimeout 以请求开始的时间为起点计算截止时间，timeout<=0时不限制
+func withTimeout(parent context.Context, start time.Time, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithDeadline(parent, start.Add(timeout))
+}
+
+// Timeout 为请求的context设置默认超时，超时后context被取消
+// handler需要自行响应ctx.Done()，如果handler在超时后仍未写入响应则返回504
+// 单个路由可以通过RouteTimeout调整，包括延长超时时间
+func Timeout(timeout time.Duration) ServerMiddleware {
+	if timeout <= 0 {
+		return nil
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request, next http.Handler) {
+		serveWithTimeout(rw, req, next, timeout)
+	}
+}
+
+func serveWithTimeout(rw http.ResponseWriter, req *http.Request, next http.Handler, timeout time.Duration) {
+	state := &timeoutState{parent: req.Context(), start: time.Now()}
+	ctx, cancel := withTimeout(req.Context(), state.start, timeout)
+	defer cancel()
+	state.ctx = context.WithValue(ctx, timeoutKey{}, state)
+
+	tw := &timeoutWriter{ResponseWriter: rw}
+	next.ServeHTTP(tw, req.WithContext(state.ctx))
+
+	if state.ctx.Err() == context.DeadlineExceeded && !tw.wroteHeader {
+		rw.WriteHeader(http.StatusGatewayTimeout)
+	}
+}
+
+// RouteTimeout 设置单个路由的超时时间，替换DefaultServerChain中的默认超时，超时时间从请求开始时计算
+// timeout<=0时该路由不限制超时(如文件下载、长轮询)
+// 配合chi使用: r.With(httpmiddleware.RouteTimeout(30 * time.Second)).Post("/export", h)
+func RouteTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			state, ok := req.Context().Value(timeoutKey{}).(*timeoutState)
+			if !ok {
+				if timeout <= 0 {
+					next.ServeHTTP(rw, req)
+					return
+				}
+				serveWithTimeout(rw, req, next, timeout)
+				return
+			}
+
+			// 从Timeout之前的context重新派生，默认超时不再影响该路由
+			ctx, cancel := withTimeout(valuesContext{Context: state.parent, values: req.Context()}, state.start, timeout)
+			defer cancel()
+			state.ctx = ctx
+			next.ServeHTTP(rw, req.WithContext(ctx))
+		})
+	}
+}
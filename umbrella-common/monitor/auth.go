@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ListenConfig 内部监听地址的配置，/internal和pprof只在该地址上提供
+type ListenConfig struct {
+	Listen       string
+	AllowedCIDRs []string // 允许访问的来源(CIDR或IP)，为空时不限制
+	BearerToken  string   // 不为空时要求请求携带"Authorization: Bearer <token>"
+	// TLS 不为空时使用TLS，要求客户端证书时只有持有CA签发证书的客户端才能访问
+	TLS *tls.Config
+}
+
+// ProbePaths 健康检查和ping接口，负载均衡和k8s探针无法携带Token，也不一定在白名单内，不做访问限制
+var ProbePaths = []string{"/internal/ping", "/internal/health/live", "/internal/health/ready"}
+
+func isProbePath(path string) bool {
+	for _, p := range ProbePaths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// ProbeHandler path是ProbePaths之一且已注册时返回对应的Handler，否则返回nil
+func ProbeHandler(path string) http.Handler {
+	if !isProbePath(path) {
+		return nil
+	}
+	return MonitorHandlers[path]
+}
+
+func parseAllowedCIDRs(cidrs []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, c := range cidrs {
+		if !strings.Contains(c, "/") {
+			if strings.Contains(c, ":") {
+				c += "/128"
+			} else {
+				c += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic("invalid allowed cidr: " + c)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func allowedIP(nets []*net.IPNet, remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Guard 按来源IP和Bearer Token限制对内部接口的访问，两者都配置时需要同时满足
+// 内部监听地址不经过代理，直接使用RemoteAddr判断来源；ProbePaths不受限制
+func Guard(config ListenConfig) func(http.Handler) http.Handler {
+	nets := parseAllowedCIDRs(config.AllowedCIDRs)
+	token := []byte(config.BearerToken)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isProbePath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(nets) > 0 && !allowedIP(nets, r.RemoteAddr) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			if len(token) > 0 {
+				auth := r.Header.Get("Authorization")
+				const prefix = "Bearer "
+				if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) ||
+					subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), token) != 1 {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="internal"`)
+					http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
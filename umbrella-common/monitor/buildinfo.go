@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var processStartTime = time.Now()
+
+// buildInfoCollector 在采集时读取GlobalVersion，Init和SetVersion的调用顺序不受限制
+type buildInfoCollector struct {
+	desc *prometheus.Desc
+}
+
+func (c *buildInfoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *buildInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	v := GlobalVersion
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 1,
+		v.GitTag, v.GitHash, v.BuildTime, runtime.Version())
+}
+
+func (m *monitor) registerBuildInfo() {
+	m.registry.MustRegister(&buildInfoCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(m.nameSpace, m.subSystem, "build_info"),
+			fmt.Sprintf("build information for %s system in %s", m.subSystem, m.nameSpace),
+			[]string{"git_tag", "git_hash", "build_time", "go_version"},
+			nil,
+		),
+	})
+
+	m.registry.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: m.nameSpace,
+			Subsystem: m.subSystem,
+			Name:      "uptime_seconds",
+			Help:      fmt.Sprintf("process uptime for %s system in %s", m.subSystem, m.nameSpace),
+		},
+		func() float64 {
+			return time.Since(processStartTime).Seconds()
+		},
+	))
+}
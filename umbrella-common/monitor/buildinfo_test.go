@@ -0,0 +1,38 @@
+package monitor
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	oldVersion, oldCache := GlobalVersion, versionJsonCache
+	defer func() { GlobalVersion, versionJsonCache = oldVersion, oldCache }()
+
+	// SetVersion可以在New之后调用，采集时读取最新的版本
+	m := New(Config{Namespace: "test", Subsystem: "buildinfo"})
+	m.SetVersion(Version{GitTag: "v1.2.0", GitHash: "abc123", BuildTime: "2026-10-19_10:00:00AM"})
+
+	expected := `
+# HELP test_buildinfo_build_info build information for buildinfo system in test
+# TYPE test_buildinfo_build_info gauge
+test_buildinfo_build_info{build_time="2026-10-19_10:00:00AM",git_hash="abc123",git_tag="v1.2.0",go_version="` + runtime.Version() + `"} 1
+`
+	assert.Nil(testutil.GatherAndCompare(m.Gatherer(), strings.NewReader(expected), "test_buildinfo_build_info"))
+
+	families, err := m.Gatherer().Gather()
+	assert.Nil(err)
+	var names []string
+	for _, f := range families {
+		names = append(names, f.GetName())
+	}
+	assert.Contains(names, "test_buildinfo_uptime_seconds")
+	// 进程启动时间由默认registry的process_start_time_seconds提供
+	assert.NotContains(names, "test_buildinfo_start_time_seconds")
+}
@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// OverflowLabelValue 超出限制的标签值统一记为other
+const OverflowLabelValue = "other"
+
+// LabelLimit 限制某个标签的取值，防止客户端可控的标签(如caller)导致指标数量失控
+type LabelLimit struct {
+	Allowlist []string // 非空时只允许列表中的值
+	MaxValues int      // 大于0时最多允许的不同取值个数，先到先得
+}
+
+// defaultLabelLimits 未配置时使用的限制，statement是归一化后的SQL语句，取值数量不可控
+var defaultLabelLimits = map[string]LabelLimit{
+	"statement": {MaxValues: 500},
+}
+
+// maxRejectedValues 最多记录的被拒绝的不同取值个数，超出后不再计数，避免占用的内存失控
+const maxRejectedValues = 10000
+
+type labelGuard struct {
+	sync.RWMutex
+	allow     map[string]struct{}
+	seen      map[string]struct{}
+	maxValues int
+
+	rejectedMu sync.Mutex
+	rejected   map[string]struct{}
+}
+
+func newLabelGuard(limit LabelLimit) *labelGuard {
+	g := &labelGuard{
+		seen:      make(map[string]struct{}),
+		maxValues: limit.MaxValues,
+		rejected:  make(map[string]struct{}),
+	}
+	if len(limit.Allowlist) > 0 {
+		g.allow = make(map[string]struct{}, len(limit.Allowlist))
+		for _, v := range limit.Allowlist {
+			g.allow[v] = struct{}{}
+		}
+	}
+	return g
+}
+
+func (g *labelGuard) accept(value string) bool {
+	if g.allow != nil {
+		_, ok := g.allow[value]
+		return ok
+	}
+	if g.maxValues <= 0 {
+		return true
+	}
+
+	g.RLock()
+	_, ok := g.seen[value]
+	g.RUnlock()
+	if ok {
+		return true
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	if _, ok := g.seen[value]; ok {
+		return true
+	}
+	if len(g.seen) >= g.maxValues {
+		return false
+	}
+	g.seen[value] = struct{}{}
+	return true
+}
+
+// reject 记录被拒绝的取值，同一个值只在第一次被拒绝时返回true
+func (g *labelGuard) reject(value string) bool {
+	g.rejectedMu.Lock()
+	defer g.rejectedMu.Unlock()
+	if _, ok := g.rejected[value]; ok || len(g.rejected) >= maxRejectedValues {
+		return false
+	}
+	g.rejected[value] = struct{}{}
+	return true
+}
+
+func (m *monitor) registerCardinality() {
+	m.labelGuards = make(map[string]*labelGuard, len(m.config.LabelLimits)+len(defaultLabelLimits))
+	for label, limit := range defaultLabelLimits {
+		m.labelGuards[label] = newLabelGuard(limit)
+	}
+	for label, limit := range m.config.LabelLimits {
+		m.labelGuards[label] = newLabelGuard(limit)
+	}
+
+	dropped := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: m.nameSpace,
+			Subsystem: m.subSystem,
+			Name:      "dropped_label_values",
+			Help:      fmt.Sprintf("distinct label values replaced by %q due to cardinality limits for %s system in %s", OverflowLabelValue, m.subSystem, m.nameSpace),
+		},
+		[]string{"label"},
+	)
+	m.registry.MustRegister(dropped)
+	m.droppedLabels = dropped
+}
+
+// limitLabel 返回允许使用的标签值，超出限制时返回OverflowLabelValue
+// 一个请求会经过多个指标，因此按不同的取值计数，同一个值只计一次
+func (m *monitor) limitLabel(label, value string) string {
+	g, ok := m.labelGuards[label]
+	if !ok || g.accept(value) {
+		return value
+	}
+
+	if g.reject(value) && m.droppedLabels != nil {
+		m.droppedLabels.WithLabelValues(label).Inc()
+	}
+	return OverflowLabelValue
+}
+
+// LimitLabel 供自定义指标使用的标签限制，monitor未初始化时原样返回
+func (m *monitor) LimitLabel(label, value string) string {
+	if m == nil {
+		return value
+	}
+	return m.limitLabel(label, value)
+}
+
+// LimitLabel 使用全局的Monitor限制标签的取值
+func LimitLabel(label, value string) string {
+	return Monitor.LimitLabel(label, value)
+}
+
+func (m *monitor) limitCallerAPI(caller, api string) (string, string) {
+	return m.limitLabel("caller", caller), m.limitLabel("api", api)
+}
+
+func (m *monitor) DroppedLabelCounter(label string) (prometheus.Counter, error) {
+	if m == nil || m.droppedLabels == nil {
+		return nil, errors.New("no dropped label counter registered")
+	}
+
+	return m.droppedLabels.GetMetricWithLabelValues(label)
+}
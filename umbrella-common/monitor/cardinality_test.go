@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	m := New(Config{
+		Namespace: "test",
+		Subsystem: "cardinality",
+		LabelLimits: map[string]LabelLimit{
+			"caller": {MaxValues: 2},
+			"api":    {Allowlist: []string{"Echo"}},
+		},
+	})
+
+	assert.Equal("a", m.limitLabel("caller", "a"))
+	assert.Equal("b", m.limitLabel("caller", "b"))
+	assert.Equal("a", m.limitLabel("caller", "a"))
+	assert.Equal(OverflowLabelValue, m.limitLabel("caller", "c"))
+
+	assert.Equal("Echo", m.limitLabel("api", "Echo"))
+	assert.Equal(OverflowLabelValue, m.limitLabel("api", "/random/path"))
+
+	assert.Equal("any", m.limitLabel("code", "any"))
+
+	// 同一个被拒绝的值只计数一次
+	m.limitLabel("caller", "c")
+	m.limitLabel("caller", "d")
+	counter, err := m.DroppedLabelCounter("caller")
+	assert.Nil(err)
+	assert.Equal(float64(2), testutil.ToFloat64(counter))
+}
+
+func TestDefaultStatementLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	m := New(Config{Namespace: "test", Subsystem: "statement"})
+	for i := 0; i < defaultLabelLimits["statement"].MaxValues; i++ {
+		assert.NotEqual(OverflowLabelValue, m.LimitLabel("statement", strconv.Itoa(i)))
+	}
+	assert.Equal(OverflowLabelValue, m.LimitLabel("statement", "select ?"))
+
+	var nilMonitor *monitor
+	assert.Equal("select ?", nilMonitor.LimitLabel("statement", "select ?"))
+}
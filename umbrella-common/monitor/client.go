@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var clientLabels = []string{"target", "api", "code"}
+
+func (m *monitor) registerClient() {
+	counter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: m.nameSpace,
+			Subsystem: m.subSystem,
+			Name:      "client_count",
+			Help:      fmt.Sprintf("outbound call counter for %s system in %s", m.subSystem, m.nameSpace),
+		},
+		clientLabels,
+	)
+	m.registry.MustRegister(counter)
+	m.clientCounter = counter
+
+	timer := prometheus.NewHistogramVec(
+		m.durationHistogramOpts("client_timer", fmt.Sprintf("outbound call timer for %s system in %s", m.subSystem, m.nameSpace)),
+		clientLabels,
+	)
+	m.registry.MustRegister(timer)
+	m.clientTimer = timer
+}
+
+func (m *monitor) ClientCounter(target, api, code string) (prometheus.Counter, error) {
+	if m == nil || m.clientCounter == nil {
+		return nil, errors.New("no client counter registered")
+	}
+
+	return m.clientCounter.GetMetricWithLabelValues(target, api, code)
+}
+
+func (m *monitor) ClientTimer(target, api, code string) (prometheus.Observer, error) {
+	if m == nil || m.clientTimer == nil {
+		return nil, errors.New("no client timer registered")
+	}
+
+	return m.clientTimer.GetMetricWithLabelValues(target, api, code)
+}
+
+// ObserveClient 记录一次对下游target的调用
+func ObserveClient(target, api, code string, cost time.Duration) {
+	if counter, _ := Monitor.ClientCounter(target, api, code); counter != nil {
+		counter.Inc()
+	}
+
+	if timer, _ := Monitor.ClientTimer(target, api, code); timer != nil {
+		timer.Observe(Monitor.durationValue(cost))
+	}
+}
+
+// 将"/pkg.Service/Method"拆分为"pkg.Service"和"Method"
+func splitFullMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+func MonitorClientInceptorUnary() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (err error) {
+		target, api := splitFullMethod(method)
+
+		start := time.Now()
+		defer func() {
+			ObserveClient(target, api, strconv.Itoa(int(grpc.Code(err))), time.Now().Sub(start))
+		}()
+
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		return
+	}
+}
+
+type monitoredClientStream struct {
+	grpc.ClientStream
+	once   sync.Once
+	done   chan struct{}
+	target string
+	api    string
+	start  time.Time
+	// serverStreams 为false时服务端只返回一个响应，收到响应即结束
+	serverStreams bool
+}
+
+func (s *monitoredClientStream) finish(err error) {
+	s.once.Do(func() {
+		if err == io.EOF {
+			err = nil
+		}
+		ObserveClient(s.target, s.api, strconv.Itoa(int(grpc.Code(err))), time.Now().Sub(s.start))
+		close(s.done)
+	})
+}
+
+// watch 调用方取消ctx或超时(包括不再读取而放弃的stream)时结束
+func (s *monitoredClientStream) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		code := codes.Canceled
+		if ctx.Err() == context.DeadlineExceeded {
+			code = codes.DeadlineExceeded
+		}
+		s.finish(status.Error(code, ctx.Err().Error()))
+	case <-s.done:
+	}
+}
+
+func (s *monitoredClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && err != io.EOF {
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *monitoredClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.finish(err)
+	}
+	return err
+}
+
+// MonitorClientInceptorStream 在stream结束时记录：RecvMsg返回错误或io.EOF，
+// 非服务端流的RPC(如client streaming的CloseAndRecv)收到响应，或者调用方的ctx结束
+func MonitorClientInceptorStream() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		target, api := splitFullMethod(method)
+
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			ObserveClient(target, api, strconv.Itoa(int(grpc.Code(err))), time.Now().Sub(start))
+			return nil, err
+		}
+
+		s := &monitoredClientStream{
+			ClientStream:  cs,
+			done:          make(chan struct{}),
+			target:        target,
+			api:           api,
+			start:         start,
+			serverStreams: desc.ServerStreams,
+		}
+		if ctx.Done() != nil {
+			go s.watch(ctx)
+		}
+		return s, nil
+	}
+}
@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+type fakeClientStream struct {
+	grpc.ClientStream
+	recvErr error
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	return s.recvErr
+}
+
+func newFakeStreamer(recvErr error) grpc.Streamer {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{recvErr: recvErr}, nil
+	}
+}
+
+func clientCount(t *testing.T, api, code string) float64 {
+	counter, err := Monitor.ClientCounter("test.Echo", api, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testutil.ToFloat64(counter)
+}
+
+func TestMonitorClientStream(t *testing.T) {
+	assert := assert.New(t)
+
+	old := Monitor
+	Monitor = New(Config{Namespace: "test", Subsystem: "client"})
+	defer func() { Monitor = old }()
+
+	interceptor := MonitorClientInceptorStream()
+
+	// client streaming: CloseAndRecv收到响应即结束
+	cs, err := interceptor(context.Background(), &grpc.StreamDesc{ClientStreams: true}, nil, "/test.Echo/Upload", newFakeStreamer(nil))
+	assert.Nil(err)
+	assert.Nil(cs.RecvMsg(nil))
+	assert.Nil(cs.RecvMsg(nil))
+	assert.Equal(float64(1), clientCount(t, "Upload", "0"))
+
+	// server streaming: 收到io.EOF时结束
+	cs, _ = interceptor(context.Background(), &grpc.StreamDesc{ServerStreams: true}, nil, "/test.Echo/Watch", newFakeStreamer(io.EOF))
+	cs.RecvMsg(nil)
+	assert.Equal(float64(1), clientCount(t, "Watch", "0"))
+
+	// 调用方放弃stream并取消ctx
+	ctx, cancel := context.WithCancel(context.Background())
+	cs, _ = interceptor(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, "/test.Echo/Abandoned", newFakeStreamer(nil))
+	cancel()
+	select {
+	case <-cs.(*monitoredClientStream).done:
+	case <-time.After(time.Second):
+	}
+	assert.Equal(float64(1), clientCount(t, "Abandoned", "1"))
+}
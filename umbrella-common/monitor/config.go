@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	defaultHistogramBuckets        = []float64{1, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000}
+	defaultSecondsHistogramBuckets = prometheus.DefBuckets
+	defaultSizeBuckets             = prometheus.ExponentialBuckets(64, 4, 8) // 64B ~ 1MB
+)
+
+type Config struct {
+	Namespace string
+	Subsystem string
+
+	// Buckets 耗时直方图的分桶，单位与Seconds一致，为空时使用默认值
+	Buckets []float64
+	// Seconds 以秒为单位记录耗时，指标名增加"_seconds"后缀，兼容通用的dashboard
+	// 默认以毫秒为单位，与旧版本保持一致
+	Seconds bool
+	// SizeBuckets 请求/响应大小直方图的分桶，单位为字节
+	SizeBuckets []float64
+
+	// NativeHistogramBucketFactor 大于1时同时开启native(sparse) histogram
+	NativeHistogramBucketFactor float64
+	NativeHistogramMaxBuckets   uint32
+
+	// LabelLimits 按标签名(caller、api、statement)限制标签的取值，statement未配置时最多500个取值
+	LabelLimits map[string]LabelLimit
+
+	// Sink api count和timer指标的后端，prometheus(默认)或statsd
+	Sink   string
+	StatsD StatsDConfig
+}
+
+func (c *Config) durationBuckets() []float64 {
+	if len(c.Buckets) > 0 {
+		return c.Buckets
+	}
+	if c.Seconds {
+		return defaultSecondsHistogramBuckets
+	}
+	return defaultHistogramBuckets
+}
+
+func (c *Config) sizeBuckets() []float64 {
+	if len(c.SizeBuckets) > 0 {
+		return c.SizeBuckets
+	}
+	return defaultSizeBuckets
+}
+
+func (m *monitor) histogramOpts(name, help string, buckets []float64) prometheus.HistogramOpts {
+	opts := prometheus.HistogramOpts{
+		Namespace: m.nameSpace,
+		Subsystem: m.subSystem,
+		Name:      name,
+		Help:      help,
+		Buckets:   buckets,
+	}
+	if m.config.NativeHistogramBucketFactor > 1 {
+		opts.NativeHistogramBucketFactor = m.config.NativeHistogramBucketFactor
+		opts.NativeHistogramMaxBucketNumber = m.config.NativeHistogramMaxBuckets
+	}
+	return opts
+}
+
+// 耗时直方图的指标名，以秒为单位时增加"_seconds"后缀
+func (m *monitor) durationName(name string) string {
+	if m.config.Seconds {
+		return name + "_seconds"
+	}
+	return name
+}
+
+func (m *monitor) durationHistogramOpts(name, help string) prometheus.HistogramOpts {
+	return m.histogramOpts(m.durationName(name), help, m.config.durationBuckets())
+}
+
+// durationValue 按配置的单位转换耗时
+func (m *monitor) durationValue(d time.Duration) float64 {
+	if m != nil && m.config.Seconds {
+		return d.Seconds()
+	}
+	return float64(d / time.Millisecond)
+}
+
+// DurationName 自定义耗时指标的名称，以秒为单位时增加"_seconds"后缀
+func DurationName(name string) string {
+	if Monitor == nil {
+		return name
+	}
+	return Monitor.durationName(name)
+}
+
+// DurationValue 按配置的单位(毫秒或秒)转换耗时，自定义的耗时指标应使用它
+func DurationValue(d time.Duration) float64 {
+	return Monitor.durationValue(d)
+}
@@ -0,0 +1,232 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"umbrella-go/umbrella-common/caller"
+)
+
+func (m *monitor) registerHTTP() {
+	counter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: m.nameSpace,
+			Subsystem: m.subSystem,
+			Name:      "http_error_code_count",
+			Help:      fmt.Sprintf("http business error code counter for %s system in %s", m.subSystem, m.nameSpace),
+		},
+		[]string{"caller", "api", "error_code"},
+	)
+	m.registry.MustRegister(counter)
+	m.httpErrorCode = counter
+}
+
+func (m *monitor) HTTPErrorCodeCounter(caller, api, errorCode string) (prometheus.Counter, error) {
+	if m == nil || m.httpErrorCode == nil {
+		return nil, errors.New("no http error code counter registered")
+	}
+
+	caller, api = m.limitCallerAPI(caller, api)
+	return m.httpErrorCode.GetMetricWithLabelValues(caller, api, errorCode)
+}
+
+// ResponseRecorder 包装http.ResponseWriter，记录实际的HTTP状态码、写入的字节数
+// 以及渲染的errors.Error中的业务错误码
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	errorCode   int
+	wroteHeader bool
+}
+
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w}
+}
+
+func (rr *ResponseRecorder) WriteHeader(code int) {
+	if !rr.wroteHeader {
+		rr.status = code
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *ResponseRecorder) Write(b []byte) (int, error) {
+	if !rr.wroteHeader {
+		rr.WriteHeader(http.StatusOK)
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += int64(n)
+	return n, err
+}
+
+func (rr *ResponseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rr *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := rr.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("http.Hijacker is not implemented")
+}
+
+// Status 未写入过响应时返回http.StatusOK，与net/http的行为一致
+func (rr *ResponseRecorder) Status() int {
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+	return rr.status
+}
+
+func (rr *ResponseRecorder) BytesWritten() int64 {
+	return rr.bytes
+}
+
+// ErrorCode 没有渲染errors.Error时返回0
+func (rr *ResponseRecorder) ErrorCode() int {
+	return rr.errorCode
+}
+
+type responseRecorderKey struct{}
+
+func ContextWithResponseRecorder(ctx context.Context, rr *ResponseRecorder) context.Context {
+	return context.WithValue(ctx, responseRecorderKey{}, rr)
+}
+
+func ResponseRecorderFromContext(ctx context.Context) *ResponseRecorder {
+	rr, _ := ctx.Value(responseRecorderKey{}).(*ResponseRecorder)
+	return rr
+}
+
+// SetRespCode 记录本次请求的业务错误码，render在渲染errors.Error时调用
+func SetRespCode(ctx context.Context, code int) {
+	if rr := ResponseRecorderFromContext(ctx); rr != nil {
+		rr.errorCode = code
+	}
+}
+
+// RequestWithRespCode 兼容旧的设置业务错误码的方法
+func RequestWithRespCode(r *http.Request, code int) *http.Request {
+	SetRespCode(r.Context(), code)
+	return r
+}
+
+func serveHTTPWithMonitor(w http.ResponseWriter, r *http.Request, handler http.Handler, api string) {
+	rr := ResponseRecorderFromContext(r.Context())
+	if rr == nil {
+		rr = NewResponseRecorder(w)
+		r = r.WithContext(ContextWithResponseRecorder(r.Context(), rr))
+	}
+
+	done := trackInFlight(api)
+	start := time.Now()
+	defer func() {
+		done()
+		cost := time.Now().Sub(start)
+		status := rr.Status()
+		// handler panic时还未写入响应，外层的Recoverer会返回500，这里按500记录后继续panic
+		p := recover()
+		if p != nil {
+			status = http.StatusInternalServerError
+			defer panic(p)
+		}
+		code := strconv.Itoa(status)
+		caller := caller.CallerNameFromContext(r.Context())
+		if caller == "" {
+			caller = "unknown"
+		}
+
+		if counter, _ := Monitor.Counter(caller, api, code); counter != nil {
+			counter.Inc()
+		}
+
+		if timer, _ := Monitor.Timer(caller, api, code); timer != nil {
+			timer.Observe(Monitor.durationValue(cost))
+		}
+
+		if rr.ErrorCode() != 0 {
+			if counter, _ := Monitor.HTTPErrorCodeCounter(caller, api, strconv.Itoa(rr.ErrorCode())); counter != nil {
+				counter.Inc()
+			}
+		}
+
+		observeSize(caller, api, int(r.ContentLength), int(rr.BytesWritten()))
+	}()
+
+	handler.ServeHTTP(rr, r)
+}
+
+func HttpHandlerWrapper(api string, handler func(w http.ResponseWriter, r *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveHTTPWithMonitor(w, r, http.HandlerFunc(handler), api)
+	}
+}
+
+// HttpMonitor chi中间件，使用匹配到的路由模板(如"/users/{id}")作为api标签
+// 未匹配到路由的请求api标签为"unknown"
+func HttpMonitor(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		serveHTTPWithMonitor(w, r, next, MatchRoutePattern(r))
+	}
+	return http.HandlerFunc(fn)
+}
+
+// RoutePattern 返回chi匹配到的完整路由模板，需要在路由匹配完成(handler执行)之后调用
+func RoutePattern(r *http.Request) string {
+	rctx := routeContext(r)
+	if rctx == nil {
+		return "unknown"
+	}
+	return joinRoutePatterns(rctx.RoutePatterns)
+}
+
+// routeContext 请求未经过chi路由(如最外层的中间件)时返回nil，chi.RouteContext在这种情况下会panic
+func routeContext(r *http.Request) *chi.Context {
+	rctx, _ := r.Context().Value(chi.RouteCtxKey).(*chi.Context)
+	return rctx
+}
+
+// MatchRoutePattern 在路由匹配之前(如中间件中)预先查找请求将要匹配的路由模板
+func MatchRoutePattern(r *http.Request) string {
+	rctx := routeContext(r)
+	if rctx == nil || rctx.Routes == nil {
+		return "unknown"
+	}
+
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+	tctx := chi.NewRouteContext()
+	if !rctx.Routes.Match(tctx, r.Method, path) {
+		return "unknown"
+	}
+	return joinRoutePatterns(tctx.RoutePatterns)
+}
+
+func joinRoutePatterns(patterns []string) string {
+	if len(patterns) == 0 {
+		return "unknown"
+	}
+
+	// 子路由挂载点会以"/*"结尾，拼接后去掉中间的通配符
+	pattern := strings.Join(patterns, "")
+	for strings.Contains(pattern, "/*/") {
+		pattern = strings.Replace(pattern, "/*/", "/", -1)
+	}
+	return pattern
+}
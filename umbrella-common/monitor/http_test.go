@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpMonitorRecordsResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		recorder *ResponseRecorder
+		pattern  string
+	)
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return HttpMonitor(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req)
+			recorder = ResponseRecorderFromContext(req.Context())
+			pattern = RoutePattern(req)
+		}))
+	})
+	r.Route("/users", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, req *http.Request) {
+			SetRespCode(req.Context(), 10001)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users/1", nil))
+
+	assert.Equal(http.StatusNotFound, w.Code)
+	assert.NotNil(recorder)
+	assert.Equal(http.StatusNotFound, recorder.Status())
+	assert.Equal(int64(len("not found")), recorder.BytesWritten())
+	assert.Equal(10001, recorder.ErrorCode())
+	assert.Equal("/users/{id}", pattern)
+}
+
+func TestHttpMonitorRepanics(t *testing.T) {
+	h := HttpMonitor(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	}))
+
+	assert.Panics(t, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
+
+func TestGuard(t *testing.T) {
+	assert := assert.New(t)
+
+	h := Guard(ListenConfig{AllowedCIDRs: []string{"10.0.0.0/8"}, BearerToken: "secret"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(path, remoteAddr, auth string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(http.StatusOK, serve("/internal/metrics", "10.1.2.3:1234", "Bearer secret"))
+	assert.Equal(http.StatusForbidden, serve("/internal/metrics", "1.2.3.4:1234", "Bearer secret"))
+	assert.Equal(http.StatusUnauthorized, serve("/internal/metrics", "10.1.2.3:1234", "Bearer wrong"))
+	assert.Equal(http.StatusUnauthorized, serve("/internal/metrics", "10.1.2.3:1234", ""))
+
+	// 探针接口不受限制
+	assert.Equal(http.StatusOK, serve("/internal/ping", "1.2.3.4:1234", ""))
+	assert.Equal(http.StatusOK, serve("/internal/health/ready", "1.2.3.4:1234", ""))
+}
+
+func TestRoutePatternWithoutRouter(t *testing.T) {
+	assert := assert.New(t)
+
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	assert.Equal("unknown", RoutePattern(req))
+	assert.Equal("unknown", MatchRoutePattern(req))
+}
@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LatencyTracker 根据timer直方图计算两次调用Quantile之间所有接口耗时的分位数
+// 只支持prometheus sink，StatsD sink没有本地的直方图数据
+type LatencyTracker struct {
+	mu      sync.Mutex
+	last    map[float64]uint64
+	lastSum uint64
+}
+
+func NewLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{}
+}
+
+func (m *monitor) timerBuckets() (map[float64]uint64, uint64, error) {
+	if m == nil {
+		return nil, 0, errors.New("monitor not initialized")
+	}
+	if _, ok := m.sink.(*prometheusSink); !ok {
+		return nil, 0, errors.New("latency quantile requires prometheus sink")
+	}
+
+	families, err := m.registry.Gather()
+	if err != nil {
+		return nil, 0, err
+	}
+	name := prometheus.BuildFQName(m.nameSpace, m.subSystem, m.durationName("timer"))
+	buckets := make(map[float64]uint64)
+	var count uint64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			h := metric.GetHistogram()
+			count += h.GetSampleCount()
+			for _, b := range h.GetBucket() {
+				buckets[b.GetUpperBound()] += b.GetCumulativeCount()
+			}
+		}
+	}
+	return buckets, count, nil
+}
+
+// Quantile 返回上次调用之后新增请求耗时的q分位数(如0.99)，期间没有请求或timer没有分桶时ok为false
+// 在桶内按线性插值计算，结果的精度取决于Buckets的配置
+func (t *LatencyTracker) Quantile(q float64) (d time.Duration, ok bool, err error) {
+	buckets, count, err := Monitor.timerBuckets()
+	if err != nil {
+		return 0, false, err
+	}
+
+	t.mu.Lock()
+	last, lastCount := t.last, t.lastSum
+	t.last, t.lastSum = buckets, count
+	t.mu.Unlock()
+
+	if count <= lastCount {
+		return 0, false, nil
+	}
+	total := float64(count - lastCount)
+
+	bounds := make([]float64, 0, len(buckets))
+	for bound := range buckets {
+		bounds = append(bounds, bound)
+	}
+	sort.Float64s(bounds)
+	if len(bounds) == 0 {
+		return 0, false, nil
+	}
+
+	rank := q * total
+	lower, lowerCount := 0.0, 0.0
+	for _, bound := range bounds {
+		c := float64(buckets[bound] - last[bound])
+		if c >= rank {
+			value := bound
+			if c > lowerCount {
+				value = lower + (bound-lower)*(rank-lowerCount)/(c-lowerCount)
+			}
+			return Monitor.durationFromValue(value), true, nil
+		}
+		lower, lowerCount = bound, c
+	}
+	// 超过最大的桶，只能返回最大桶的上界
+	return Monitor.durationFromValue(lower), true, nil
+}
+
+// MaxLatencyBucket 返回timer直方图最大的有限分桶上界，超过该值的耗时无法区分，
+// monitor未初始化或没有有限分桶时ok为false
+func MaxLatencyBucket() (d time.Duration, ok bool) {
+	if Monitor == nil {
+		return 0, false
+	}
+	max := math.Inf(-1)
+	for _, bound := range Monitor.config.durationBuckets() {
+		if !math.IsInf(bound, 0) && bound > max {
+			max = bound
+		}
+	}
+	if math.IsInf(max, -1) {
+		return 0, false
+	}
+	return Monitor.durationFromValue(max), true
+}
+
+// durationFromValue durationValue的逆运算
+func (m *monitor) durationFromValue(v float64) time.Duration {
+	if math.IsInf(v, 0) {
+		v = 0
+	}
+	if m != nil && m.config.Seconds {
+		return time.Duration(v * float64(time.Second))
+	}
+	return time.Duration(v * float64(time.Millisecond))
+}
@@ -0,0 +1,49 @@
+package monitor
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyQuantile(t *testing.T) {
+	assert := assert.New(t)
+
+	old := Monitor
+	defer func() { Monitor = old }()
+
+	Monitor = New(Config{Namespace: "test", Subsystem: "latency", Buckets: []float64{10, 100}})
+	tracker := NewLatencyTracker()
+	_, ok, err := tracker.Quantile(0.99)
+	assert.Nil(err)
+	assert.False(ok)
+
+	timer, err := Monitor.Timer("web", "Echo", "0")
+	assert.Nil(err)
+	for i := 0; i < 100; i++ {
+		timer.Observe(50)
+	}
+	p99, ok, err := tracker.Quantile(0.99)
+	assert.Nil(err)
+	assert.True(ok)
+	assert.True(p99 > 10*time.Millisecond && p99 <= 100*time.Millisecond)
+
+	max, ok := MaxLatencyBucket()
+	assert.True(ok)
+	assert.Equal(100*time.Millisecond, max)
+
+	// 只有+Inf分桶时无法计算分位数
+	Monitor = New(Config{Namespace: "test", Subsystem: "latency", Buckets: []float64{math.Inf(1)}})
+	tracker = NewLatencyTracker()
+	timer, err = Monitor.Timer("web", "Echo", "0")
+	assert.Nil(err)
+	timer.Observe(50)
+	_, ok, err = tracker.Quantile(0.99)
+	assert.Nil(err)
+	assert.False(ok)
+
+	_, ok = MaxLatencyBucket()
+	assert.False(ok)
+}
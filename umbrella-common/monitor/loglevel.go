@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"umbrella-go/umbrella-common/json"
+	"umbrella-go/umbrella-common/log"
+)
+
+func init() {
+	MonitorHandlers["/internal/loglevel"] = http.HandlerFunc(LogLevelHandler)
+}
+
+type logLevelRequest struct {
+	Level  string `json:"level"`
+	Logger string `json:"logger"` // 为空且Caller为空时调整全局级别
+	Caller string `json:"caller"`
+	TTL    string `json:"ttl"` // 如"10m"，为空时不自动恢复
+}
+
+// LogLevelHandler 查看和临时调整日志级别
+// GET 返回当前的级别和所有调整
+// PUT/POST {"level": "debug", "logger": "redis", "caller": "", "ttl": "10m"} 调整级别
+// DELETE ?logger=redis&caller= 取消调整，都为空时取消全局的调整
+func LogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var req logLevelRequest
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 4096))
+		if err == nil {
+			err = json.Unmarshal(body, &req)
+		}
+		if err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		level, err := log.ParseLevel(req.Level)
+		if err != nil || req.Level == "" {
+			http.Error(w, "invalid level: "+req.Level, http.StatusBadRequest)
+			return
+		}
+		var ttl time.Duration
+		if req.TTL != "" {
+			if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl < 0 {
+				http.Error(w, "invalid ttl: "+req.TTL, http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch {
+		case req.Caller != "":
+			log.SetCallerLevel(req.Caller, level, ttl)
+		case req.Logger != "":
+			log.SetLoggerLevel(req.Logger, level, ttl)
+		default:
+			log.SetGlobalLevel(level, ttl)
+		}
+		log.Info(r.Context(), "log level changed", log.String("level", level.String()),
+			log.String("logger", req.Logger), log.String("target_caller", req.Caller), log.String("ttl", req.TTL))
+	case http.MethodDelete:
+		query := r.URL.Query()
+		log.ResetLevel(query.Get("logger"), query.Get("caller"))
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := json.Marshal(log.Levels())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
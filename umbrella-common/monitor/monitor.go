@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -9,15 +10,18 @@ import (
 	"github.com/go-chi/chi"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"umbrella-go/umbrella-common/caller"
+	"umbrella-go/umbrella-common/log"
 )
 
 var MonitorHandlers = make(map[string]http.Handler)
 
 func RegisterHandlers(r *chi.Mux) {
 	if len(MonitorHandlers) == 0 {
-		//log.Fatal("cannot start when have no handlers")
+		log.Fatal(context.Background(), "cannot start when have no handlers")
 	}
 
 	for k, v := range MonitorHandlers {
@@ -29,16 +33,36 @@ func RegisterHandlers(r *chi.Mux) {
 	}
 }
 
+// InitAndListen 在listenAddr上提供内部接口，不做访问限制
 func InitAndListen(listenAddr string) {
-	r := chi.NewRouter()
-	RegisterHandlers(r)
+	InitAndListenWithConfig(ListenConfig{Listen: listenAddr})
+}
+
+// InitAndListenWithConfig 在单独的地址上提供/internal和pprof接口，并按配置限制访问来源和Token
+// 这些接口不应再注册到对外的路由上
+func InitAndListenWithConfig(config ListenConfig) {
+	srv := NewServer(config)
 
 	go func() {
-		//log.Fatal()
-		http.ListenAndServe(listenAddr, r)
+		listenAndServe := srv.ListenAndServe
+		if srv.TLSConfig != nil {
+			listenAndServe = func() error { return srv.ListenAndServeTLS("", "") }
+		}
+		if err := listenAndServe(); err != nil {
+			log.Fatal(context.Background(), "start monitor server failed", log.String("listen", config.Listen), log.Err(err))
+		}
 	}()
 }
 
+// NewServer 创建提供内部接口的http.Server，由调用方负责启动和关闭
+func NewServer(config ListenConfig) *http.Server {
+	r := chi.NewRouter()
+	r.Use(Guard(config))
+	RegisterHandlers(r)
+
+	return &http.Server{Addr: config.Listen, Handler: r, TLSConfig: config.TLS}
+}
+
 func MonitorInceptorUnary() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 		api := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
@@ -47,9 +71,16 @@ func MonitorInceptorUnary() grpc.UnaryServerInterceptor {
 			caller = "unknown"
 		}
 
+		done := trackInFlight(api)
 		start := time.Now()
 		defer func() {
+			done()
 			cost := time.Now().Sub(start)
+			// handler panic时由外层的recovery转换为codes.Internal，这里按codes.Internal记录后继续panic
+			if p := recover(); p != nil {
+				err = status.Error(codes.Internal, fmt.Sprint(p))
+				defer panic(p)
+			}
 			code := strconv.Itoa(int(grpc.Code(err)))
 
 			if counter, _ := Monitor.Counter(caller, api, code); counter != nil { // TODO: caller
@@ -57,51 +88,13 @@ func MonitorInceptorUnary() grpc.UnaryServerInterceptor {
 			}
 
 			if timer, _ := Monitor.Timer(caller, api, code); timer != nil {
-				timer.Observe(float64(cost / time.Millisecond))
-			}
-		}()
-
-		resp, err = handler(ctx, req)
-		return
-	}
-}
-
-func HttpHandlerWrapper(api string, handler func(w http.ResponseWriter, r *http.Request)) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		defer func() {
-			cost := time.Now().Sub(start)
-			ctx := r.Context()
-			code := strconv.Itoa(respCodeFromContext(ctx))
-			caller := caller.CallerNameFromContext(ctx)
-			if caller == "" {
-				caller = "unknown"
-			}
-
-			if counter, _ := Monitor.Counter(caller, api, code); counter != nil { // TODO: caller
-				counter.Inc()
+				timer.Observe(Monitor.durationValue(cost))
 			}
 
-			if timer, _ := Monitor.Timer(caller, api, code); timer != nil {
-				timer.Observe(float64(cost / time.Millisecond))
-			}
+			observeSize(caller, api, protoSize(req), protoSize(resp))
 		}()
 
-		handler(w, r)
+		resp, err = handler(ctx, req)
 		return
 	}
 }
-
-type httpResponseCodeKey struct{}
-
-func respCodeFromContext(ctx context.Context) int {
-	code, ok := ctx.Value(httpResponseCodeKey{}).(int)
-	if !ok {
-		return 0
-	}
-	return code
-}
-
-func RequestWithRespCode(r *http.Request, code int) *http.Request {
-	return r.WithContext(context.WithValue(r.Context(), httpResponseCodeKey{}, code))
-}
@@ -0,0 +1,30 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func (m *monitor) registerPanic() {
+	counter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: m.nameSpace,
+			Subsystem: m.subSystem,
+			Name:      "panic_count",
+			Help:      fmt.Sprintf("recovered panic counter for %s system in %s", m.subSystem, m.nameSpace),
+		},
+		[]string{"api"},
+	)
+	m.registry.MustRegister(counter)
+	m.panic = counter
+}
+
+func (m *monitor) PanicCounter(api string) (prometheus.Counter, error) {
+	if m == nil || m.panic == nil {
+		return nil, errors.New("no panic counter registered")
+	}
+
+	return m.panic.GetMetricWithLabelValues(api)
+}
@@ -0,0 +1,14 @@
+package monitor
+
+import (
+	"umbrella-go/umbrella-common/debugutil"
+)
+
+// pprof只挂载在内部监听地址上
+func init() {
+	for k, v := range debugutil.PProfHandlers("/debug/pprof") {
+		MonitorHandlers[k] = v
+	}
+	// 未单独列出的profile(如allocs)由Index按名称处理
+	MonitorHandlers["/debug/pprof/*"] = MonitorHandlers["/debug/pprof/"]
+}
@@ -2,83 +2,106 @@ package monitor
 
 import (
 	"errors"
-	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-var defaultHistogramBuckets = []float64{1, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000}
-
 var labels = []string{"caller", "api", "code"}
 
 type monitor struct {
 	nameSpace string
 	subSystem string
+	config    Config
+	registry  *prometheus.Registry
+
+	sink Sink
+
+	resilience resilience
+	panic      *prometheus.CounterVec
+	streamMsg  *prometheus.CounterVec
+
+	clientCounter *prometheus.CounterVec
+	clientTimer   *prometheus.HistogramVec
 
-	counter *prometheus.CounterVec
-	timer   *prometheus.HistogramVec
+	httpErrorCode *prometheus.CounterVec
+
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	inFlight     *prometheus.GaugeVec
+
+	labelGuards   map[string]*labelGuard
+	droppedLabels *prometheus.CounterVec
 }
 
 var Monitor *monitor
 
 func init() {
-	MonitorHandlers["/internal/metrics"] = promhttp.Handler()
+	MonitorHandlers["/internal/metrics"] = http.HandlerFunc(metricsHandler)
 	MonitorHandlers["/internal/ping"] = Monitor.PingHandler()
 }
 
+// Init 使用默认配置初始化
 func Init(nameSpace, subSystem string) {
+	InitWithConfig(Config{Namespace: nameSpace, Subsystem: subSystem})
+}
+
+// InitWithConfig 初始化全局的Monitor，每次调用都会使用新的registry，可以重复调用
+func InitWithConfig(config Config) {
+	Monitor = New(config)
+}
+
+// New 创建使用独立registry的monitor实例，不影响全局的Monitor，主要用于测试
+func New(config Config) *monitor {
 	m := &monitor{
-		nameSpace: nameSpace,
-		subSystem: subSystem,
+		nameSpace: config.Namespace,
+		subSystem: config.Subsystem,
+		config:    config,
+		registry:  prometheus.NewRegistry(),
 	}
 
-	// TODO  分析prometheus原理
-	// register api counter
-	counter := prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Namespace: nameSpace,
-			Subsystem: subSystem,
-			Name:      "count",
-			Help:      fmt.Sprintf("api counter for %s system in %s", m.subSystem, m.nameSpace),
-		},
-		labels,
-	)
-	prometheus.MustRegister(counter)
-	m.counter = counter
-
-	// register api timer
-	timer := prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Namespace: m.nameSpace,
-			Subsystem: m.subSystem,
-			Name:      "timer",
-			Help:      fmt.Sprintf("api timer for %s system in %s", m.subSystem, m.nameSpace),
-			Buckets:   defaultHistogramBuckets,
-		},
-		labels,
-	)
-	prometheus.MustRegister(timer)
-	m.timer = timer
-
-	Monitor = m
+	m.registerSink()
+
+	m.registerResilience()
+	m.registerPanic()
+	m.registerStream()
+	m.registerClient()
+	m.registerHTTP()
+	m.registerSize()
+	m.registerCardinality()
+	m.registerBuildInfo()
+
+	return m
 }
 
-func (m *monitor) Counter(caller, api, code string) (prometheus.Counter, error) {
-	if m.counter == nil {
+func (m *monitor) Counter(caller, api, code string) (CounterMetric, error) {
+	if m == nil || m.sink == nil {
 		return nil, errors.New("no counter registered")
 	}
 
-	return m.counter.GetMetricWithLabelValues(caller, api, code)
+	caller, api = m.limitCallerAPI(caller, api)
+	return m.sink.Counter(caller, api, code)
 }
 
 func (m *monitor) Timer(caller, api, code string) (prometheus.Observer, error) {
-	if m.timer == nil {
+	if m == nil || m.sink == nil {
 		return nil, errors.New("no timer registered")
 	}
 
-	return m.timer.GetMetricWithLabelValues(caller, api, code)
+	caller, api = m.limitCallerAPI(caller, api)
+	return m.sink.Timer(caller, api, code)
+}
+
+// Close 关闭指标后端，StatsD会在关闭前发送剩余的数据
+func (m *monitor) Close() error {
+	if m == nil {
+		return nil
+	}
+	if c, ok := m.sink.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 // SetVersion 兼容旧的设置版本的方法
@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var errNoMonitor = errors.New("monitor is not initialized")
+
+var (
+	gatherersLock  sync.RWMutex
+	extraGatherers []prometheus.Gatherer
+)
+
+// RegisterGatherer 将其他registry合并到/internal/metrics的输出中
+func RegisterGatherer(g prometheus.Gatherer) {
+	gatherersLock.Lock()
+	defer gatherersLock.Unlock()
+	extraGatherers = append(extraGatherers, g)
+}
+
+// Gatherer 返回monitor实例的registry，测试中可以用它检查指标
+func (m *monitor) Gatherer() prometheus.Gatherer {
+	return m.registry
+}
+
+// Registerer 返回monitor实例的registry，用于注册自定义的prometheus.Collector
+func (m *monitor) Registerer() prometheus.Registerer {
+	return m.registry
+}
+
+func (m *monitor) Namespace() string {
+	return m.nameSpace
+}
+
+func (m *monitor) Subsystem() string {
+	return m.subSystem
+}
+
+// 合并全局默认registry(Go runtime、process等)、Monitor的registry以及额外注册的registry
+func gatherers() prometheus.Gatherers {
+	gatherersLock.RLock()
+	defer gatherersLock.RUnlock()
+
+	gs := prometheus.Gatherers{prometheus.DefaultGatherer}
+	if m := Monitor; m != nil {
+		gs = append(gs, m.registry)
+	}
+	return append(gs, extraGatherers...)
+}
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	promhttp.HandlerFor(gatherers(), promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// 未设置Namespace和Subsystem时使用monitor的配置
+func (m *monitor) fillNames(namespace, subsystem *string) {
+	if *namespace == "" {
+		*namespace = m.nameSpace
+	}
+	if *subsystem == "" {
+		*subsystem = m.subSystem
+	}
+}
+
+func (m *monitor) NewCounter(opts prometheus.CounterOpts, labels ...string) (*prometheus.CounterVec, error) {
+	if m == nil {
+		return nil, errNoMonitor
+	}
+
+	m.fillNames(&opts.Namespace, &opts.Subsystem)
+	counter := prometheus.NewCounterVec(opts, labels)
+	if err := m.registry.Register(counter); err != nil {
+		return nil, err
+	}
+	return counter, nil
+}
+
+func (m *monitor) NewGauge(opts prometheus.GaugeOpts, labels ...string) (*prometheus.GaugeVec, error) {
+	if m == nil {
+		return nil, errNoMonitor
+	}
+
+	m.fillNames(&opts.Namespace, &opts.Subsystem)
+	gauge := prometheus.NewGaugeVec(opts, labels)
+	if err := m.registry.Register(gauge); err != nil {
+		return nil, err
+	}
+	return gauge, nil
+}
+
+// NewHistogram 未设置Buckets时使用配置的耗时分桶
+func (m *monitor) NewHistogram(opts prometheus.HistogramOpts, labels ...string) (*prometheus.HistogramVec, error) {
+	if m == nil {
+		return nil, errNoMonitor
+	}
+
+	m.fillNames(&opts.Namespace, &opts.Subsystem)
+	if len(opts.Buckets) == 0 {
+		opts.Buckets = m.config.durationBuckets()
+	}
+	histogram := prometheus.NewHistogramVec(opts, labels)
+	if err := m.registry.Register(histogram); err != nil {
+		return nil, err
+	}
+	return histogram, nil
+}
+
+// NewCounter 在全局的Monitor上注册业务自定义的counter
+func NewCounter(opts prometheus.CounterOpts, labels ...string) (*prometheus.CounterVec, error) {
+	return Monitor.NewCounter(opts, labels...)
+}
+
+// NewGauge 在全局的Monitor上注册业务自定义的gauge
+func NewGauge(opts prometheus.GaugeOpts, labels ...string) (*prometheus.GaugeVec, error) {
+	return Monitor.NewGauge(opts, labels...)
+}
+
+// NewHistogram 在全局的Monitor上注册业务自定义的histogram
+func NewHistogram(opts prometheus.HistogramOpts, labels ...string) (*prometheus.HistogramVec, error) {
+	return Monitor.NewHistogram(opts, labels...)
+}
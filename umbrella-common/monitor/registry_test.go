@@ -0,0 +1,39 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIsolatedRegistry(t *testing.T) {
+	assert := assert.New(t)
+
+	// 多次创建不会因为重复注册而panic
+	m1 := New(Config{Namespace: "test", Subsystem: "monitor"})
+	m2 := New(Config{Namespace: "test", Subsystem: "monitor"})
+
+	counter, err := m1.NewCounter(prometheus.CounterOpts{Name: "orders", Help: "orders"}, "status")
+	assert.Nil(err)
+	counter.WithLabelValues("paid").Inc()
+
+	_, err = m1.NewCounter(prometheus.CounterOpts{Name: "orders", Help: "orders"}, "status")
+	assert.NotNil(err)
+
+	families, err := m1.Gatherer().Gather()
+	assert.Nil(err)
+	found := false
+	for _, f := range families {
+		if f.GetName() == "test_monitor_orders" {
+			found = true
+		}
+	}
+	assert.True(found)
+
+	families, err = m2.Gatherer().Gather()
+	assert.Nil(err)
+	for _, f := range families {
+		assert.NotEqual("test_monitor_orders", f.GetName())
+	}
+}
@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gRPC客户端重试、对冲、熔断相关的指标
+type resilience struct {
+	retry   *prometheus.CounterVec
+	hedge   *prometheus.CounterVec
+	breaker *prometheus.GaugeVec
+}
+
+func (m *monitor) registerResilience() {
+	retry := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: m.nameSpace,
+			Subsystem: m.subSystem,
+			Name:      "client_retry_count",
+			Help:      fmt.Sprintf("grpc client retry counter for %s system in %s", m.subSystem, m.nameSpace),
+		},
+		[]string{"method", "code"},
+	)
+	m.registry.MustRegister(retry)
+
+	hedge := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: m.nameSpace,
+			Subsystem: m.subSystem,
+			Name:      "client_hedge_count",
+			Help:      fmt.Sprintf("grpc client hedged request counter for %s system in %s", m.subSystem, m.nameSpace),
+		},
+		[]string{"method"},
+	)
+	m.registry.MustRegister(hedge)
+
+	// 0: closed, 1: open, 2: half-open
+	breaker := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: m.nameSpace,
+			Subsystem: m.subSystem,
+			Name:      "client_circuit_breaker_state",
+			Help:      fmt.Sprintf("grpc client circuit breaker state (0 closed, 1 open, 2 half-open) for %s system in %s", m.subSystem, m.nameSpace),
+		},
+		[]string{"method"},
+	)
+	m.registry.MustRegister(breaker)
+
+	m.resilience = resilience{
+		retry:   retry,
+		hedge:   hedge,
+		breaker: breaker,
+	}
+}
+
+func (m *monitor) RetryCounter(method, code string) (prometheus.Counter, error) {
+	if m == nil || m.resilience.retry == nil {
+		return nil, errors.New("no retry counter registered")
+	}
+
+	return m.resilience.retry.GetMetricWithLabelValues(method, code)
+}
+
+func (m *monitor) HedgeCounter(method string) (prometheus.Counter, error) {
+	if m == nil || m.resilience.hedge == nil {
+		return nil, errors.New("no hedge counter registered")
+	}
+
+	return m.resilience.hedge.GetMetricWithLabelValues(method)
+}
+
+func (m *monitor) CircuitBreakerState(method string) (prometheus.Gauge, error) {
+	if m == nil || m.resilience.breaker == nil {
+		return nil, errors.New("no circuit breaker gauge registered")
+	}
+
+	return m.resilience.breaker.GetMetricWithLabelValues(method)
+}
@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	SinkPrometheus = "prometheus"
+	SinkStatsD     = "statsd"
+)
+
+// CounterMetric prometheus.Counter以及StatsD的counter都满足该接口
+type CounterMetric interface {
+	Inc()
+	Add(float64)
+}
+
+// Sink api count和timer指标的输出后端，由Config.Sink选择
+type Sink interface {
+	Counter(caller, api, code string) (CounterMetric, error)
+	Timer(caller, api, code string) (prometheus.Observer, error)
+}
+
+type prometheusSink struct {
+	counter *prometheus.CounterVec
+	timer   *prometheus.HistogramVec
+}
+
+func (s *prometheusSink) Counter(caller, api, code string) (CounterMetric, error) {
+	return s.counter.GetMetricWithLabelValues(caller, api, code)
+}
+
+func (s *prometheusSink) Timer(caller, api, code string) (prometheus.Observer, error) {
+	return s.timer.GetMetricWithLabelValues(caller, api, code)
+}
+
+func (m *monitor) newPrometheusSink() *prometheusSink {
+	// register api counter
+	counter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: m.nameSpace,
+			Subsystem: m.subSystem,
+			Name:      "count",
+			Help:      fmt.Sprintf("api counter for %s system in %s", m.subSystem, m.nameSpace),
+		},
+		labels,
+	)
+	m.registry.MustRegister(counter)
+
+	// register api timer
+	timer := prometheus.NewHistogramVec(
+		m.durationHistogramOpts("timer", fmt.Sprintf("api timer for %s system in %s", m.subSystem, m.nameSpace)),
+		labels,
+	)
+	m.registry.MustRegister(timer)
+
+	return &prometheusSink{
+		counter: counter,
+		timer:   timer,
+	}
+}
+
+func (m *monitor) registerSink() {
+	switch m.config.Sink {
+	case "", SinkPrometheus:
+		m.sink = m.newPrometheusSink()
+	case SinkStatsD:
+		sink, err := newStatsDSink(m.nameSpace, m.subSystem, m.config.StatsD, m.config.Seconds)
+		if err != nil {
+			panic(err)
+		}
+		m.sink = sink
+	default:
+		panic(fmt.Sprintf("unknown monitor sink: %s", m.config.Sink))
+	}
+}
@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var sizeLabels = []string{"caller", "api"}
+
+func (m *monitor) registerSize() {
+	requestSize := prometheus.NewHistogramVec(
+		m.histogramOpts("request_size_bytes", fmt.Sprintf("api request size for %s system in %s", m.subSystem, m.nameSpace), m.config.sizeBuckets()),
+		sizeLabels,
+	)
+	m.registry.MustRegister(requestSize)
+	m.requestSize = requestSize
+
+	responseSize := prometheus.NewHistogramVec(
+		m.histogramOpts("response_size_bytes", fmt.Sprintf("api response size for %s system in %s", m.subSystem, m.nameSpace), m.config.sizeBuckets()),
+		sizeLabels,
+	)
+	m.registry.MustRegister(responseSize)
+	m.responseSize = responseSize
+
+	inFlight := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: m.nameSpace,
+			Subsystem: m.subSystem,
+			Name:      "in_flight",
+			Help:      fmt.Sprintf("api in-flight requests for %s system in %s", m.subSystem, m.nameSpace),
+		},
+		[]string{"api"},
+	)
+	m.registry.MustRegister(inFlight)
+	m.inFlight = inFlight
+}
+
+func (m *monitor) RequestSize(caller, api string) (prometheus.Observer, error) {
+	if m == nil || m.requestSize == nil {
+		return nil, errors.New("no request size histogram registered")
+	}
+
+	caller, api = m.limitCallerAPI(caller, api)
+	return m.requestSize.GetMetricWithLabelValues(caller, api)
+}
+
+func (m *monitor) ResponseSize(caller, api string) (prometheus.Observer, error) {
+	if m == nil || m.responseSize == nil {
+		return nil, errors.New("no response size histogram registered")
+	}
+
+	caller, api = m.limitCallerAPI(caller, api)
+	return m.responseSize.GetMetricWithLabelValues(caller, api)
+}
+
+func (m *monitor) InFlight(api string) (prometheus.Gauge, error) {
+	if m == nil || m.inFlight == nil {
+		return nil, errors.New("no in-flight gauge registered")
+	}
+
+	api = m.limitLabel("api", api)
+	return m.inFlight.GetMetricWithLabelValues(api)
+}
+
+// trackInFlight 增加api的in-flight计数，返回的函数用于在请求结束时减少计数
+func trackInFlight(api string) func() {
+	gauge, _ := Monitor.InFlight(api)
+	if gauge == nil {
+		return func() {}
+	}
+
+	gauge.Inc()
+	return gauge.Dec
+}
+
+func observeSize(caller, api string, requestSize, responseSize int) {
+	if observer, _ := Monitor.RequestSize(caller, api); observer != nil && requestSize >= 0 {
+		observer.Observe(float64(requestSize))
+	}
+
+	if observer, _ := Monitor.ResponseSize(caller, api); observer != nil && responseSize >= 0 {
+		observer.Observe(float64(responseSize))
+	}
+}
+
+// 非proto消息返回-1，不记录大小
+func protoSize(v interface{}) int {
+	if msg, ok := v.(proto.Message); ok {
+		return proto.Size(msg)
+	}
+	return -1
+}
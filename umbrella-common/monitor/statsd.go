@@ -0,0 +1,247 @@
+package monitor
+
+import (
+	"bytes"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	FlavorStatsD    = "statsd"
+	FlavorDogStatsD = "dogstatsd"
+
+	defaultStatsDFlushInterval = 10 * time.Second
+	defaultStatsDPacketSize    = 1432 // 以太网MTU下不分片的UDP payload大小
+	defaultStatsDMaxSamples    = 1000
+)
+
+type StatsDConfig struct {
+	Addr   string
+	Flavor string // statsd或dogstatsd，statsd不支持tag，标签会拼接到指标名中
+	// FlushInterval 客户端聚合的时长，counter在一个周期内合并为一条
+	FlushInterval time.Duration
+	MaxPacketSize int
+	// MaxSamples 每个timer在一个周期内最多保留的样本数，超出时蓄水池抽样，按采样率上报
+	MaxSamples int
+}
+
+type timerSamples struct {
+	values []float64
+	total  int
+}
+
+type statsdSink struct {
+	prefix  string
+	config  StatsDConfig
+	toMs    float64
+	conn    net.Conn
+	mu      sync.Mutex
+	counts  map[string]float64
+	timers  map[string]*timerSamples
+	closing chan struct{}
+	done    chan struct{}
+}
+
+func newStatsDSink(nameSpace, subSystem string, config StatsDConfig, seconds bool) (*statsdSink, error) {
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultStatsDFlushInterval
+	}
+	if config.MaxPacketSize <= 0 {
+		config.MaxPacketSize = defaultStatsDPacketSize
+	}
+	if config.MaxSamples <= 0 {
+		config.MaxSamples = defaultStatsDMaxSamples
+	}
+
+	conn, err := net.Dial("udp", config.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []string
+	for _, p := range []string{nameSpace, subSystem} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+
+	s := &statsdSink{
+		prefix:  strings.Join(parts, "."),
+		config:  config,
+		toMs:    1,
+		conn:    conn,
+		counts:  make(map[string]float64),
+		timers:  make(map[string]*timerSamples),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	// StatsD的timer单位固定为毫秒
+	if seconds {
+		s.toMs = 1000
+	}
+
+	go s.loop()
+	return s, nil
+}
+
+// 生成不含类型和值的指标名部分，dogstatsd的tag放在最后由format处理
+func (s *statsdSink) key(name, caller, api, code string) string {
+	metric := name
+	if s.prefix != "" {
+		metric = s.prefix + "." + name
+	}
+
+	if s.config.Flavor == FlavorDogStatsD {
+		return metric + "|#caller:" + sanitizeTag(caller) + ",api:" + sanitizeTag(api) + ",code:" + sanitizeTag(code)
+	}
+	return metric + "." + sanitizeName(caller) + "." + sanitizeName(api) + "." + sanitizeName(code)
+}
+
+func sanitizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '.', '/', ' ':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func sanitizeTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', ' ':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// 将key拆分为指标名和dogstatsd的tag部分
+func formatLine(key, value, typ string, rate float64) string {
+	name, tags := key, ""
+	if i := strings.Index(key, "|#"); i >= 0 {
+		name, tags = key[:i], key[i:]
+	}
+
+	line := name + ":" + value + "|" + typ
+	if rate < 1 {
+		line += "|@" + strconv.FormatFloat(rate, 'f', -1, 64)
+	}
+	return line + tags
+}
+
+type statsdCounter struct {
+	sink *statsdSink
+	key  string
+}
+
+func (c *statsdCounter) Inc() {
+	c.Add(1)
+}
+
+func (c *statsdCounter) Add(v float64) {
+	c.sink.mu.Lock()
+	c.sink.counts[c.key] += v
+	c.sink.mu.Unlock()
+}
+
+type statsdTimer struct {
+	sink *statsdSink
+	key  string
+}
+
+func (t *statsdTimer) Observe(v float64) {
+	s := t.sink
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ts, ok := s.timers[t.key]
+	if !ok {
+		ts = &timerSamples{}
+		s.timers[t.key] = ts
+	}
+	ts.total++
+	if len(ts.values) < s.config.MaxSamples {
+		ts.values = append(ts.values, v*s.toMs)
+		return
+	}
+	// 蓄水池抽样，周期内每个样本被保留的概率相同，不会只保留周期开始时的样本
+	if i := rand.Intn(ts.total); i < len(ts.values) {
+		ts.values[i] = v * s.toMs
+	}
+}
+
+func (s *statsdSink) Counter(caller, api, code string) (CounterMetric, error) {
+	return &statsdCounter{sink: s, key: s.key("count", caller, api, code)}, nil
+}
+
+func (s *statsdSink) Timer(caller, api, code string) (prometheus.Observer, error) {
+	return &statsdTimer{sink: s, key: s.key("timer", caller, api, code)}, nil
+}
+
+func (s *statsdSink) loop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.closing:
+			s.flush()
+			return
+		}
+	}
+}
+
+func (s *statsdSink) flush() {
+	s.mu.Lock()
+	counts, timers := s.counts, s.timers
+	s.counts = make(map[string]float64, len(counts))
+	s.timers = make(map[string]*timerSamples, len(timers))
+	s.mu.Unlock()
+
+	var lines []string
+	for key, v := range counts {
+		lines = append(lines, formatLine(key, strconv.FormatFloat(v, 'f', -1, 64), "c", 1))
+	}
+	for key, ts := range timers {
+		rate := float64(len(ts.values)) / float64(ts.total)
+		for _, v := range ts.values {
+			lines = append(lines, formatLine(key, strconv.FormatFloat(v, 'f', -1, 64), "ms", rate))
+		}
+	}
+	sort.Strings(lines)
+
+	// 多条指标用换行合并到一个UDP包中，不超过MaxPacketSize
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > s.config.MaxPacketSize {
+			s.conn.Write(buf.Bytes())
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+	if buf.Len() > 0 {
+		s.conn.Write(buf.Bytes())
+	}
+}
+
+// Close 发送剩余的数据并关闭连接
+func (s *statsdSink) Close() error {
+	close(s.closing)
+	<-s.done
+	return s.conn.Close()
+}
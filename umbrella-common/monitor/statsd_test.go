@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsDSink(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	m := New(Config{
+		Namespace: "test",
+		Subsystem: "statsd",
+		Sink:      SinkStatsD,
+		StatsD: StatsDConfig{
+			Addr:          conn.LocalAddr().String(),
+			Flavor:        FlavorDogStatsD,
+			FlushInterval: time.Hour,
+		},
+	})
+
+	counter, err := m.Counter("web", "Echo", "0")
+	assert.Nil(err)
+	counter.Inc()
+	counter.Inc()
+
+	timer, err := m.Timer("web", "Echo", "0")
+	assert.Nil(err)
+	timer.Observe(12)
+
+	assert.Nil(m.Close())
+
+	buf := make([]byte, 1500)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(err)
+
+	lines := strings.Split(string(buf[:n]), "\n")
+	assert.Equal([]string{
+		"test.statsd.count:2|c|#caller:web,api:Echo,code:0",
+		"test.statsd.timer:12|ms|#caller:web,api:Echo,code:0",
+	}, lines)
+}
+
+func TestStatsDTimerReservoir(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	m := New(Config{
+		Namespace: "test",
+		Subsystem: "statsd",
+		Sink:      SinkStatsD,
+		StatsD: StatsDConfig{
+			Addr:          conn.LocalAddr().String(),
+			FlushInterval: time.Hour,
+			MaxSamples:    10,
+		},
+	})
+	defer m.Close()
+
+	timer, err := m.Timer("web", "Echo", "0")
+	assert.Nil(err)
+	for i := 0; i < 1000; i++ {
+		timer.Observe(float64(i))
+	}
+
+	s := m.sink.(*statsdSink)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ts := range s.timers {
+		assert.Equal(1000, ts.total)
+		assert.Equal(10, len(ts.values))
+
+		// 后面的样本也有机会被保留
+		later := 0
+		for _, v := range ts.values {
+			if v >= 10 {
+				later++
+			}
+		}
+		assert.True(later > 0)
+	}
+}
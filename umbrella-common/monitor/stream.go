@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"umbrella-go/umbrella-common/caller"
+)
+
+func (m *monitor) registerStream() {
+	counter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: m.nameSpace,
+			Subsystem: m.subSystem,
+			Name:      "stream_msg_count",
+			Help:      fmt.Sprintf("grpc stream message counter for %s system in %s", m.subSystem, m.nameSpace),
+		},
+		[]string{"caller", "api", "direction"},
+	)
+	m.registry.MustRegister(counter)
+	m.streamMsg = counter
+}
+
+// StreamMsgCounter direction为sent或received
+func (m *monitor) StreamMsgCounter(caller, api, direction string) (prometheus.Counter, error) {
+	if m == nil || m.streamMsg == nil {
+		return nil, errors.New("no stream message counter registered")
+	}
+
+	caller, api = m.limitCallerAPI(caller, api)
+	return m.streamMsg.GetMetricWithLabelValues(caller, api, direction)
+}
+
+// monitoredServerStream 每收发一条消息即累加计数，长时间运行的stream在结束前也能观察到消息量
+type monitoredServerStream struct {
+	grpc.ServerStream
+	sent     prometheus.Counter
+	received prometheus.Counter
+}
+
+func (s *monitoredServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil && s.sent != nil {
+		s.sent.Inc()
+	}
+	return err
+}
+
+func (s *monitoredServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil && s.received != nil {
+		s.received.Inc()
+	}
+	return err
+}
+
+// MonitorInceptorStream 记录每个stream的持续时长、收发消息数以及最终的状态码
+func MonitorInceptorStream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		api := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+		caller := caller.CallerNameFromContext(ss.Context())
+		if caller == "" {
+			caller = "unknown"
+		}
+
+		ms := &monitoredServerStream{ServerStream: ss}
+		ms.sent, _ = Monitor.StreamMsgCounter(caller, api, "sent")
+		ms.received, _ = Monitor.StreamMsgCounter(caller, api, "received")
+		done := trackInFlight(api)
+		start := time.Now()
+		defer func() {
+			done()
+			cost := time.Now().Sub(start)
+			// handler panic时由外层的recovery转换为codes.Internal，这里按codes.Internal记录后继续panic
+			if p := recover(); p != nil {
+				err = status.Error(codes.Internal, fmt.Sprint(p))
+				defer panic(p)
+			}
+			code := strconv.Itoa(int(grpc.Code(err)))
+
+			if counter, _ := Monitor.Counter(caller, api, code); counter != nil {
+				counter.Inc()
+			}
+
+			if timer, _ := Monitor.Timer(caller, api, code); timer != nil {
+				timer.Observe(Monitor.durationValue(cost))
+			}
+		}()
+
+		err = handler(srv, ms)
+		return
+	}
+}
@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	recv    int
+	sendErr error
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeServerStream) SendMsg(m interface{}) error {
+	return s.sendErr
+}
+
+// RecvMsg 前recv次成功，之后返回io.EOF
+func (s *fakeServerStream) RecvMsg(m interface{}) error {
+	if s.recv == 0 {
+		return io.EOF
+	}
+	s.recv--
+	return nil
+}
+
+func streamMsgCount(t *testing.T, api, direction string) float64 {
+	counter, err := Monitor.StreamMsgCounter("unknown", api, direction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testutil.ToFloat64(counter)
+}
+
+func TestMonitorInceptorStream(t *testing.T) {
+	assert := assert.New(t)
+
+	old := Monitor
+	Monitor = New(Config{Namespace: "test", Subsystem: "stream"})
+	defer func() { Monitor = old }()
+
+	interceptor := MonitorInceptorStream()
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Echo/Chat"}
+	err := interceptor(nil, &fakeServerStream{recv: 2}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		for ss.RecvMsg(nil) == nil {
+		}
+		assert.Nil(ss.SendMsg(nil))
+		// stream结束前计数已经可见
+		assert.Equal(float64(2), streamMsgCount(t, "Chat", "received"))
+		assert.Equal(float64(1), streamMsgCount(t, "Chat", "sent"))
+		return status.Error(codes.NotFound, "not found")
+	})
+	assert.Equal(codes.NotFound, status.Code(err))
+	assert.Equal(float64(2), streamMsgCount(t, "Chat", "received"))
+	assert.Equal(float64(1), streamMsgCount(t, "Chat", "sent"))
+
+	counter, err := Monitor.Counter("unknown", "Chat", "5")
+	assert.Nil(err)
+	assert.Equal(float64(1), testutil.ToFloat64(counter.(prometheus.Counter)))
+
+	// 发送失败的消息不计数
+	sendErr := errors.New("send failed")
+	err = interceptor(nil, &fakeServerStream{sendErr: sendErr}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		return ss.SendMsg(nil)
+	})
+	assert.Equal(sendErr, err)
+	assert.Equal(float64(1), streamMsgCount(t, "Chat", "sent"))
+	counter, err = Monitor.Counter("unknown", "Chat", "2")
+	assert.Nil(err)
+	assert.Equal(float64(1), testutil.ToFloat64(counter.(prometheus.Counter)))
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
 
 	"umbrella-go/umbrella-common/json"
 )
@@ -14,6 +16,19 @@ type Version struct {
 	GitTag    string `json:"git_tag"`
 	GitHash   string `json:"git_hash"`
 	BuildTime string `json:"build_time"`
+
+	// GoVersion 由InitVersion自动填充
+	GoVersion string `json:"go_version,omitempty"`
+	// Dependencies 依赖的版本，glide编译时取不到module信息，由ParseDependencies解析ldflags注入的glide.lock版本
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+
+	// ConfigHash 加载的配置文件内容的hash，用于确认各实例的配置是否一致
+	ConfigHash string `json:"config_hash,omitempty"`
+}
+
+type Dependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
 }
 
 var (
@@ -22,11 +37,26 @@ var (
 )
 
 func InitVersion(v Version) {
+	v.GoVersion = runtime.Version()
+
 	GlobalVersion = v
 	bs, _ := json.Marshal(v)
 	versionJsonCache = bs
 }
 
+// ParseDependencies 解析Makefile通过ldflags注入的依赖版本，格式为path@version,path@version
+func ParseDependencies(s string) []Dependency {
+	var deps []Dependency
+	for _, item := range strings.Split(s, ",") {
+		i := strings.LastIndex(item, "@")
+		if i <= 0 {
+			continue
+		}
+		deps = append(deps, Dependency{Path: item[:i], Version: item[i+1:]})
+	}
+	return deps
+}
+
 func init() {
 	MonitorHandlers["/internal/version"] = http.HandlerFunc(GetVersionHandler)
 
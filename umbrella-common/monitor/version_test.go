@@ -0,0 +1,62 @@
+package monitor
+
+import (
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"umbrella-go/umbrella-common/json"
+)
+
+func TestParseDependencies(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(ParseDependencies(""))
+	assert.Equal([]Dependency{
+		{Path: "github.com/go-chi/chi", Version: "v3.3.4"},
+		{Path: "golang.org/x/net", Version: "8a410e7b638d"},
+	}, ParseDependencies("github.com/go-chi/chi@v3.3.4,golang.org/x/net@8a410e7b638d,invalid,@v1"))
+}
+
+func TestVersionHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	oldVersion, oldCache := GlobalVersion, versionJsonCache
+	defer func() { GlobalVersion, versionJsonCache = oldVersion, oldCache }()
+
+	InitVersion(Version{
+		GitTag:       "v1.2.0",
+		GitHash:      "abc123",
+		BuildTime:    "2026-10-19_10:00:00AM",
+		Dependencies: ParseDependencies("github.com/go-chi/chi@v3.3.4"),
+		ConfigHash:   "e3b0c442",
+	})
+
+	w := httptest.NewRecorder()
+	GetVersionHandler(w, httptest.NewRequest("GET", "/internal/version", nil))
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+
+	var v map[string]interface{}
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &v))
+	assert.Equal(map[string]interface{}{
+		"git_tag":    "v1.2.0",
+		"git_hash":   "abc123",
+		"build_time": "2026-10-19_10:00:00AM",
+		"go_version": runtime.Version(),
+		"dependencies": []interface{}{
+			map[string]interface{}{"path": "github.com/go-chi/chi", "version": "v3.3.4"},
+		},
+		"config_hash": "e3b0c442",
+	}, v)
+
+	// 未设置的可选字段不输出
+	InitVersion(Version{GitTag: "v1.2.0"})
+	w = httptest.NewRecorder()
+	GetVersionHandler(w, httptest.NewRequest("GET", "/internal/version", nil))
+	v = nil
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &v))
+	assert.NotContains(v, "dependencies")
+	assert.NotContains(v, "config_hash")
+}
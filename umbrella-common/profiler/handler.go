@@ -0,0 +1,91 @@
+package profiler
+
+import (
+	"net/http"
+	"strings"
+
+	"umbrella-go/umbrella-common/json"
+	"umbrella-go/umbrella-common/monitor"
+)
+
+// Default 通过Start创建的全局Profiler，未启动时/internal/profiles返回503
+var Default *Profiler
+
+func init() {
+	monitor.MonitorHandlers["/internal/profiles"] = http.HandlerFunc(ProfilesHandler)
+}
+
+// Start 创建并启动全局的Profiler
+func Start(config Config) error {
+	p, err := New(config)
+	if err != nil {
+		return err
+	}
+	p.Start()
+	Default = p
+	return nil
+}
+
+// Stop 停止全局的Profiler，会等待正在进行的采集结束
+func Stop() {
+	if Default != nil {
+		Default.Stop()
+	}
+}
+
+// ProfilesHandler 查看和下载已采集的profile
+// GET 按时间从新到旧列出所有profile
+// GET ?name=xxx 下载指定的profile，可以直接用go tool pprof打开
+// POST ?kind=heap&kind=goroutine 立即采集，kind为空时采集配置的所有profile
+func ProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	p := Default
+	if p == nil {
+		http.Error(w, "profiler not started", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if name := r.URL.Query().Get("name"); name != "" {
+			path, ok := p.path(name)
+			if !ok {
+				http.Error(w, "invalid profile name", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+			http.ServeFile(w, r, path)
+			return
+		}
+	case http.MethodPost:
+		kinds := r.URL.Query()["kind"]
+		if len(kinds) == 0 {
+			kinds = p.config.Profiles
+		}
+		if err := p.Capture(ReasonManual, kinds...); err != nil {
+			status := http.StatusInternalServerError
+			if err == ErrCaptureInProgress {
+				status = http.StatusConflict
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+	default:
+		w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodPost}, ", "))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	profiles, err := p.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data, err := json.Marshal(profiles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,323 @@
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"umbrella-go/umbrella-common/log"
+	"umbrella-go/umbrella-common/monitor"
+)
+
+const (
+	ProfileCPU       = "cpu"
+	ProfileHeap      = "heap"
+	ProfileGoroutine = "goroutine"
+	ProfileMutex     = "mutex"
+	ProfileBlock     = "block"
+)
+
+const (
+	ReasonPeriodic   = "periodic"
+	ReasonGoroutines = "goroutines"
+	ReasonHeap       = "heap"
+	ReasonLatency    = "latency"
+	ReasonManual     = "manual"
+)
+
+const profileExt = ".pb.gz"
+
+var ErrCaptureInProgress = errors.New("profile capture in progress")
+
+type Config struct {
+	Dir         string        // 保存profile的目录，为空时使用os.TempDir()/profiles
+	MaxFiles    int           // 目录中最多保留的文件数，超过时删除最早的，为0时使用100
+	Interval    time.Duration // 定期采集的间隔，为0时不定期采集
+	Profiles    []string      // 定期采集的profile，为空时采集cpu、heap、goroutine和mutex
+	CPUDuration time.Duration // 每次采集CPU profile的时长，为0时使用10秒
+
+	CheckInterval      time.Duration // 检查阈值的间隔，为0时使用10秒
+	Cooldown           time.Duration // 同一原因两次触发采集的最小间隔，为0时使用5分钟
+	GoroutineThreshold int           // goroutine数超过该值时采集goroutine profile，为0时不检查
+	HeapGrowthRatio    float64       // HeapInuse超过基线的倍数时采集heap profile，小于等于1时不检查
+	P99Threshold       time.Duration // 接口耗时p99超过该值时采集cpu、goroutine和mutex profile，为0时不检查
+}
+
+type Profile struct {
+	Name   string    `json:"name"`
+	Kind   string    `json:"kind"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+	Size   int64     `json:"size"`
+}
+
+type Profiler struct {
+	config Config
+	logger *log.Logger
+
+	capturing int32
+	mu        sync.Mutex
+	triggered map[string]time.Time
+	heapBase  uint64
+
+	latency *monitor.LatencyTracker
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func New(config Config) (*Profiler, error) {
+	if config.Dir == "" {
+		config.Dir = filepath.Join(os.TempDir(), "profiles")
+	}
+	if config.MaxFiles <= 0 {
+		config.MaxFiles = 100
+	}
+	if len(config.Profiles) == 0 {
+		config.Profiles = []string{ProfileCPU, ProfileHeap, ProfileGoroutine, ProfileMutex}
+	}
+	if config.CPUDuration <= 0 {
+		config.CPUDuration = 10 * time.Second
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = 10 * time.Second
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 5 * time.Minute
+	}
+	for _, kind := range config.Profiles {
+		if kind != ProfileCPU && pprof.Lookup(kind) == nil {
+			return nil, fmt.Errorf("unknown profile: %s", kind)
+		}
+	}
+	// p99按timer直方图插值计算，不会超过最大的分桶上界，超过时阈值永远不会触发
+	if max, ok := monitor.MaxLatencyBucket(); ok && config.P99Threshold > max {
+		return nil, fmt.Errorf("p99 threshold %s exceeds the largest latency bucket %s", config.P99Threshold, max)
+	}
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	p := &Profiler{
+		config:    config,
+		logger:    log.Named("profiler"),
+		triggered: make(map[string]time.Time),
+		stop:      make(chan struct{}),
+	}
+	if config.P99Threshold > 0 {
+		p.latency = monitor.NewLatencyTracker()
+	}
+	return p, nil
+}
+
+// Start 启动定期采集和阈值检查
+func (p *Profiler) Start() {
+	p.heapBase = heapInuse()
+
+	if p.config.Interval > 0 {
+		p.loop(p.config.Interval, func() {
+			p.captureAsync(ReasonPeriodic, p.config.Profiles...)
+		})
+	}
+	if p.config.GoroutineThreshold > 0 || p.config.HeapGrowthRatio > 1 || p.latency != nil {
+		p.loop(p.config.CheckInterval, p.check)
+	}
+}
+
+func (p *Profiler) Stop() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+func (p *Profiler) loop(interval time.Duration, fn func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+func heapInuse() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapInuse
+}
+
+func (p *Profiler) check() {
+	ctx := context.Background()
+
+	if p.config.GoroutineThreshold > 0 {
+		if n := runtime.NumGoroutine(); n > p.config.GoroutineThreshold {
+			p.trigger(ReasonGoroutines, log.Int("goroutines", n), ProfileGoroutine)
+		}
+	}
+
+	if p.config.HeapGrowthRatio > 1 {
+		inuse := heapInuse()
+		if base := p.heapBase; base > 0 && float64(inuse) > float64(base)*p.config.HeapGrowthRatio {
+			if p.trigger(ReasonHeap, log.Int64("heap_inuse", int64(inuse)), ProfileHeap) {
+				// 以本次采集时的用量作为新的基线，避免持续增长时重复触发
+				p.heapBase = inuse
+			}
+		}
+	}
+
+	if p.latency != nil {
+		p99, ok, err := p.latency.Quantile(0.99)
+		if err != nil {
+			p.logger.Warn(ctx, "get p99 latency failed", log.Err(err))
+		} else if ok && p99 > p.config.P99Threshold {
+			p.trigger(ReasonLatency, log.Duration("p99", p99), ProfileCPU, ProfileGoroutine, ProfileMutex)
+		}
+	}
+}
+
+// trigger 同一原因在Cooldown内只触发一次
+func (p *Profiler) trigger(reason string, field log.Field, kinds ...string) bool {
+	now := time.Now()
+	p.mu.Lock()
+	if last, ok := p.triggered[reason]; ok && now.Sub(last) < p.config.Cooldown {
+		p.mu.Unlock()
+		return false
+	}
+	p.triggered[reason] = now
+	p.mu.Unlock()
+
+	p.logger.Warn(context.Background(), "threshold crossed, capturing profiles", log.String("reason", reason), field)
+	p.captureAsync(reason, kinds...)
+	return true
+}
+
+func (p *Profiler) captureAsync(reason string, kinds ...string) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := p.Capture(reason, kinds...); err != nil {
+			p.logger.Warn(context.Background(), "capture profiles failed", log.String("reason", reason), log.Err(err))
+		}
+	}()
+}
+
+// Capture 采集指定的profile并保存到目录中，同一时间只允许一次采集
+func (p *Profiler) Capture(reason string, kinds ...string) error {
+	if !atomic.CompareAndSwapInt32(&p.capturing, 0, 1) {
+		return ErrCaptureInProgress
+	}
+	defer atomic.StoreInt32(&p.capturing, 0)
+
+	now := time.Now()
+	var errs []string
+	for _, kind := range kinds {
+		if err := p.captureOne(now, reason, kind); err != nil {
+			errs = append(errs, kind+": "+err.Error())
+		}
+	}
+	p.rotate()
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (p *Profiler) captureOne(now time.Time, reason, kind string) error {
+	buf := new(bytes.Buffer)
+	if kind == ProfileCPU {
+		if err := pprof.StartCPUProfile(buf); err != nil {
+			return err
+		}
+		select {
+		case <-time.After(p.config.CPUDuration):
+		case <-p.stop:
+		}
+		pprof.StopCPUProfile()
+	} else {
+		profile := pprof.Lookup(kind)
+		if profile == nil {
+			return fmt.Errorf("unknown profile: %s", kind)
+		}
+		if err := profile.WriteTo(buf, 0); err != nil {
+			return err
+		}
+	}
+
+	name := fmt.Sprintf("%s-%s-%s%s", now.UTC().Format("20060102T150405.000"), reason, kind, profileExt)
+	return ioutil.WriteFile(filepath.Join(p.config.Dir, name), buf.Bytes(), 0644)
+}
+
+// rotate 按文件名(以时间开头)删除最早的profile
+func (p *Profiler) rotate() {
+	profiles, err := p.List()
+	if err != nil {
+		return
+	}
+	for i := p.config.MaxFiles; i < len(profiles); i++ {
+		os.Remove(filepath.Join(p.config.Dir, profiles[i].Name))
+	}
+}
+
+// List 按时间从新到旧返回已采集的profile
+func (p *Profiler) List() ([]Profile, error) {
+	infos, err := ioutil.ReadDir(p.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []Profile
+	for _, info := range infos {
+		if profile, ok := parseProfileName(info.Name()); ok {
+			profile.Size = info.Size()
+			profiles = append(profiles, profile)
+		}
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name > profiles[j].Name
+	})
+	return profiles, nil
+}
+
+func parseProfileName(name string) (Profile, bool) {
+	if !strings.HasSuffix(name, profileExt) {
+		return Profile{}, false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(name, profileExt), "-", 3)
+	if len(parts) != 3 {
+		return Profile{}, false
+	}
+	t, err := time.Parse("20060102T150405.000", parts[0])
+	if err != nil {
+		return Profile{}, false
+	}
+	return Profile{Name: name, Time: t, Reason: parts[1], Kind: parts[2]}, true
+}
+
+// path 返回profile的完整路径，name必须是List返回的文件名，防止访问目录之外的文件
+func (p *Profiler) path(name string) (string, bool) {
+	if name != filepath.Base(name) {
+		return "", false
+	}
+	if _, ok := parseProfileName(name); !ok {
+		return "", false
+	}
+	return filepath.Join(p.config.Dir, name), true
+}
@@ -0,0 +1,56 @@
+package profiler
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+func TestCaptureAndRotate(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "profiler")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	p, err := New(Config{Dir: dir, MaxFiles: 2})
+	assert.Nil(err)
+
+	assert.Nil(p.Capture(ReasonManual, ProfileHeap, ProfileGoroutine))
+	time.Sleep(2 * time.Millisecond)
+	assert.Nil(p.Capture(ReasonManual, ProfileMutex))
+
+	profiles, err := p.List()
+	assert.Nil(err)
+	assert.Equal(2, len(profiles))
+	assert.Equal(ProfileMutex, profiles[0].Kind)
+	assert.Equal(ReasonManual, profiles[0].Reason)
+	assert.Equal(ProfileHeap, profiles[1].Kind)
+
+	_, ok := p.path(profiles[0].Name)
+	assert.True(ok)
+	_, ok = p.path("../" + profiles[0].Name)
+	assert.False(ok)
+}
+
+func TestP99ThresholdAboveLargestBucket(t *testing.T) {
+	assert := assert.New(t)
+
+	old := monitor.Monitor
+	defer func() { monitor.Monitor = old }()
+	monitor.Monitor = monitor.New(monitor.Config{Namespace: "test", Subsystem: "profiler", Buckets: []float64{10, 100}})
+
+	dir, err := ioutil.TempDir("", "profiler")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	_, err = New(Config{Dir: dir, P99Threshold: time.Second})
+	assert.NotNil(err)
+	_, err = New(Config{Dir: dir, P99Threshold: 50 * time.Millisecond})
+	assert.Nil(err)
+}
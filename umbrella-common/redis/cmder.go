@@ -3,7 +3,7 @@ package redis
 import (
 	"context"
 
-	"git.meiqia.com/triones/compass/redis"
+	"github.com/fzzy/radix/redis"
 )
 
 type Cmder func(ctx context.Context, cmd string, args []interface{}) *redis.Reply
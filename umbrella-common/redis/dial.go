@@ -8,6 +8,22 @@ import (
 
 type Option func(*Client)
 
+func WrapCmder(cw CmderWrapper) Option {
+	return func(c *Client) {
+		if cw != nil {
+			c.cmder = cw.Wrap(c.defaultCmd)
+		}
+	}
+}
+
+func WrapPipeliner(pw PipelinerWrapper) Option {
+	return func(c *Client) {
+		if pw != nil {
+			c.pipeliner = pw.Wrap(c.defaultPipeline)
+		}
+	}
+}
+
 func WrapClient(c *redis.Client, opts ...Option) *Client {
 	client := &Client{
 		client: c,
@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fzzy/radix/redis"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+// redisMetrics 所有Wrapper共用同一组指标，以name标签区分
+type redisMetrics struct {
+	registry     prometheus.Registerer // 注册指标的monitor的registry
+	cmdLatency   *prometheus.HistogramVec
+	cmdErrors    *prometheus.CounterVec
+	pipelineSize *prometheus.HistogramVec
+}
+
+var (
+	redisMetricsCurrent atomic.Value // *redisMetrics
+	redisMetricsLock    sync.Mutex
+	redisMetricsLoaded  = make(map[prometheus.Registerer]*redisMetrics)
+)
+
+// loadRedisMetrics 返回注册在当前monitor.Monitor上的指标，第一次调用时注册，
+// monitor.Monitor被替换(如测试中)后重新注册到新的monitor上，monitor未初始化时返回nil，不记录指标
+// Wrapper可以在monitor.Init之前创建
+func loadRedisMetrics() *redisMetrics {
+	m := monitor.Monitor
+	if m == nil {
+		return nil
+	}
+	registry := m.Registerer()
+	if metrics, _ := redisMetricsCurrent.Load().(*redisMetrics); metrics != nil && metrics.registry == registry {
+		return metrics
+	}
+
+	redisMetricsLock.Lock()
+	defer redisMetricsLock.Unlock()
+	metrics, ok := redisMetricsLoaded[registry]
+	if !ok {
+		metrics = &redisMetrics{registry: registry}
+		metrics.cmdLatency, _ = m.NewHistogram(prometheus.HistogramOpts{
+			Name: monitor.DurationName("redis_cmd_duration"),
+			Help: "redis command latency, pipelines are recorded with cmd PIPELINE",
+		}, "name", "cmd")
+		metrics.cmdErrors, _ = m.NewCounter(prometheus.CounterOpts{
+			Name: "redis_cmd_error_count",
+			Help: "redis command error counter",
+		}, "name", "cmd")
+		metrics.pipelineSize, _ = m.NewHistogram(prometheus.HistogramOpts{
+			Name:    "redis_pipeline_size",
+			Help:    "number of commands in a redis pipeline",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		}, "name")
+		redisMetricsLoaded[registry] = metrics
+	}
+	redisMetricsCurrent.Store(metrics)
+	return metrics
+}
+
+func (metrics *redisMetrics) observeCmd(name, cmd string, start time.Time, errors int) {
+	if metrics.cmdLatency != nil {
+		metrics.cmdLatency.WithLabelValues(name, cmd).Observe(monitor.DurationValue(time.Now().Sub(start)))
+	}
+	if errors > 0 && metrics.cmdErrors != nil {
+		metrics.cmdErrors.WithLabelValues(name, cmd).Add(float64(errors))
+	}
+}
+
+// MetricsCmderWrapper 记录每个命令的耗时和错误数，name为redis实例的名称
+// 错误只统计ErrorReply，key不存在返回的NilReply不算错误
+func MetricsCmderWrapper(name string) CmderWrapper {
+	return func(next Cmder, ctx context.Context, cmd string, args []interface{}) *redis.Reply {
+		metrics := loadRedisMetrics()
+		if metrics == nil {
+			return next(ctx, cmd, args)
+		}
+
+		start := time.Now()
+		reply := next(ctx, cmd, args)
+
+		errors := 0
+		if reply == nil || reply.Type == redis.ErrorReply {
+			errors = 1
+		}
+		metrics.observeCmd(name, strings.ToUpper(cmd), start, errors)
+		return reply
+	}
+}
+
+// MetricsPipelinerWrapper 记录pipeline的批量大小以及整体的耗时和其中出错的命令数
+func MetricsPipelinerWrapper(name string) PipelinerWrapper {
+	return func(next Pipeliner, ctx context.Context, reqs []*Request) []*redis.Reply {
+		metrics := loadRedisMetrics()
+		if metrics == nil {
+			return next(ctx, reqs)
+		}
+
+		if metrics.pipelineSize != nil {
+			metrics.pipelineSize.WithLabelValues(name).Observe(float64(len(reqs)))
+		}
+
+		start := time.Now()
+		replies := next(ctx, reqs)
+
+		errors := 0
+		for _, reply := range replies {
+			if reply == nil || reply.Type == redis.ErrorReply {
+				errors++
+			}
+		}
+		metrics.observeCmd(name, "PIPELINE", start, errors)
+		return replies
+	}
+}
@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis"
+	radix "github.com/fzzy/radix/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+func sampleCount(t *testing.T, histogram *prometheus.HistogramVec, values ...string) uint64 {
+	var m dto.Metric
+	if err := histogram.WithLabelValues(values...).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestMetricsWrappers(t *testing.T) {
+	assert := assert.New(t)
+
+	old := monitor.Monitor
+	monitor.Monitor = monitor.New(monitor.Config{Namespace: "test", Subsystem: "redis"})
+	defer func() { monitor.Monitor = old }()
+
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	c, err := Dial("tcp", s.Addr(), WrapCmder(MetricsCmderWrapper("cache")), WrapPipeliner(MetricsPipelinerWrapper("cache")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	assert.Nil(c.Cmd(ctx, "set", "name", "umbrella").Err)
+	assert.Nil(c.Cmd(ctx, "SET", "count", "1").Err)
+	// key不存在返回NilReply，不算错误
+	assert.Equal(radix.NilReply, c.Cmd(ctx, "get", "missing").Type)
+	assert.Equal(radix.ErrorReply, c.Cmd(ctx, "incr", "name").Type)
+
+	metrics := loadRedisMetrics()
+	assert.Equal(uint64(2), sampleCount(t, metrics.cmdLatency, "cache", "SET"))
+	assert.Equal(uint64(1), sampleCount(t, metrics.cmdLatency, "cache", "GET"))
+	assert.Equal(float64(0), testutil.ToFloat64(metrics.cmdErrors.WithLabelValues("cache", "GET")))
+	assert.Equal(float64(1), testutil.ToFloat64(metrics.cmdErrors.WithLabelValues("cache", "INCR")))
+
+	// pipeline整体记录一次耗时，错误数为其中出错的命令数
+	replies := c.Pipeline(ctx, []*Request{
+		NewRequest("incr", "count"),
+		NewRequest("incr", "name"),
+		NewRequest("get", "count"),
+	})
+	if assert.Equal(3, len(replies)) {
+		n, err := replies[0].Int()
+		assert.Nil(err)
+		assert.Equal(2, n)
+		assert.Equal(radix.ErrorReply, replies[1].Type)
+	}
+	assert.Equal(uint64(1), sampleCount(t, metrics.cmdLatency, "cache", "PIPELINE"))
+	assert.Equal(float64(1), testutil.ToFloat64(metrics.cmdErrors.WithLabelValues("cache", "PIPELINE")))
+	assert.Equal(uint64(1), sampleCount(t, metrics.pipelineSize, "cache"))
+
+	// monitor未初始化时不记录指标
+	monitor.Monitor = nil
+	assert.Nil(c.Cmd(ctx, "get", "name").Err)
+	assert.Equal(uint64(2), sampleCount(t, metrics.cmdLatency, "cache", "SET"))
+}
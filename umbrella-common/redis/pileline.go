@@ -3,7 +3,7 @@ package redis
 import (
 	"context"
 
-	"git.meiqia.com/triones/compass/redis"
+	"github.com/fzzy/radix/redis"
 )
 
 type Pipeliner func(ctx context.Context, reqs []*Request) []*redis.Reply
@@ -3,9 +3,12 @@ package pool
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/fzzy/radix/extra/pool"
+	"github.com/prometheus/client_golang/prometheus"
 
+	"umbrella-go/umbrella-common/monitor"
 	"umbrella-go/umbrella-common/redis"
 )
 
@@ -17,6 +20,52 @@ type (
 type Pool struct {
 	pool *pool.Pool
 	opts []redis.Option
+	name string
+}
+
+// poolMetrics 所有连接池共用同一组指标，以name标签区分
+type poolMetrics struct {
+	registry prometheus.Registerer // 注册指标的monitor的registry
+	wait     *prometheus.HistogramVec
+}
+
+var (
+	poolMetricsCurrent atomic.Value // *poolMetrics
+	poolMetricsLock    sync.Mutex
+	poolMetricsLoaded  = make(map[prometheus.Registerer]*poolMetrics)
+)
+
+// loadPoolMetrics 返回注册在当前monitor.Monitor上的指标，monitor.Monitor被替换后重新注册，
+// monitor未初始化时返回nil
+func loadPoolMetrics() *poolMetrics {
+	m := monitor.Monitor
+	if m == nil {
+		return nil
+	}
+	registry := m.Registerer()
+	if metrics, _ := poolMetricsCurrent.Load().(*poolMetrics); metrics != nil && metrics.registry == registry {
+		return metrics
+	}
+
+	poolMetricsLock.Lock()
+	defer poolMetricsLock.Unlock()
+	metrics, ok := poolMetricsLoaded[registry]
+	if !ok {
+		metrics = &poolMetrics{registry: registry}
+		metrics.wait, _ = m.NewHistogram(prometheus.HistogramOpts{
+			Name: monitor.DurationName("redis_pool_wait"),
+			Help: "time spent waiting to check out a connection from the redis pool",
+		}, "name")
+		poolMetricsLoaded[registry] = metrics
+	}
+	poolMetricsCurrent.Store(metrics)
+	return metrics
+}
+
+// EnableMetrics 记录从连接池获取连接的等待时长，name为连接池的名称，获取连接失败(如连接池为空时拨号失败)不记录
+// 可以在monitor.Init之前调用，指标在monitor初始化之后第一次获取连接时注册
+func (p *Pool) EnableMetrics(name string) {
+	p.name = name
 }
 
 func WrapPool(p *pool.Pool, opts ...redis.Option) *Pool {
@@ -35,10 +84,16 @@ func (p *Pool) CarefullyPut(conn *redis.Client, potentialErr *error) {
 }
 
 func (p *Pool) Get() (*redis.Client, error) {
+	start := time.Now()
 	c, err := p.pool.Get()
 	if err != nil {
 		return nil, err
 	}
+	if p.name != "" {
+		if metrics := loadPoolMetrics(); metrics != nil && metrics.wait != nil {
+			metrics.wait.WithLabelValues(p.name).Observe(monitor.DurationValue(time.Now().Sub(start)))
+		}
+	}
 
 	return redis.WrapClient(c, p.opts...), nil
 }
@@ -51,6 +106,12 @@ func (p *Pool) Empty() {
 	p.pool.Empty()
 }
 
+// Close 关闭连接池中所有空闲的连接，用于进程退出时释放资源
+func (p *Pool) Close() error {
+	p.pool.Empty()
+	return nil
+}
+
 func NewCustomPool(network, addr string, size int, df pool.DialFunc, opts ...redis.Option) (*Pool, error) {
 	p, err := pool.NewCustomPool(network, addr, size, df)
 	if err != nil {
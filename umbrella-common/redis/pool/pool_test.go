@@ -6,8 +6,11 @@ import (
 
 	"github.com/alicebob/miniredis"
 	radix "github.com/fzzy/radix/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 
+	"umbrella-go/umbrella-common/monitor"
 	"umbrella-go/umbrella-common/redis"
 )
 
@@ -19,10 +22,10 @@ func TestPool(t *testing.T) {
 	defer s.Close()
 
 	var lastRequest redis.Request
-	cw := func(next redis.Cmder, ctx context.Context, cmd string, args ...interface{}) *radix.Reply {
+	cw := func(next redis.Cmder, ctx context.Context, cmd string, args []interface{}) *radix.Reply {
 		lastRequest.Cmd = cmd
 		lastRequest.Args = args
-		return next(ctx, cmd, args...)
+		return next(ctx, cmd, args)
 	}
 	pool, err := NewPool("tcp", s.Addr(), 10, redis.WrapCmder(cw))
 	if err != nil {
@@ -77,3 +80,46 @@ func TestLazyPool(t *testing.T) {
 	assert.Equal(t, "PONG", r)
 	pool.CarefullyPut(c, &err)
 }
+
+func TestPoolMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	old := monitor.Monitor
+	monitor.Monitor = monitor.New(monitor.Config{Namespace: "test", Subsystem: "pool"})
+	defer func() { monitor.Monitor = old }()
+
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := s.Addr()
+
+	pool, err := NewPool("tcp", addr, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Empty()
+	pool.EnableMetrics("main")
+
+	waitCount := func() uint64 {
+		var m dto.Metric
+		if err := loadPoolMetrics().wait.WithLabelValues("main").(prometheus.Metric).Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetHistogram().GetSampleCount()
+	}
+
+	c, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(uint64(1), waitCount())
+
+	// 连接池为空时重新拨号，redis已关闭，获取失败不记录等待时长
+	s.Close()
+	c2, err := pool.Get()
+	assert.NotNil(err)
+	assert.Nil(c2)
+	assert.Equal(uint64(1), waitCount())
+	pool.Put(c)
+}
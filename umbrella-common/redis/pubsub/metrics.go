@@ -0,0 +1,70 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fzzy/radix/extra/pubsub"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+// pubsubMetrics 所有SubClient共用同一组指标，以name标签区分
+type pubsubMetrics struct {
+	registry prometheus.Registerer // 注册指标的monitor的registry
+	messages *prometheus.CounterVec
+}
+
+var (
+	pubsubMetricsCurrent atomic.Value // *pubsubMetrics
+	pubsubMetricsLock    sync.Mutex
+	pubsubMetricsLoaded  = make(map[prometheus.Registerer]*pubsubMetrics)
+)
+
+// loadPubsubMetrics 返回注册在当前monitor.Monitor上的指标，monitor.Monitor被替换后重新注册，
+// monitor未初始化时返回nil
+func loadPubsubMetrics() *pubsubMetrics {
+	m := monitor.Monitor
+	if m == nil {
+		return nil
+	}
+	registry := m.Registerer()
+	if metrics, _ := pubsubMetricsCurrent.Load().(*pubsubMetrics); metrics != nil && metrics.registry == registry {
+		return metrics
+	}
+
+	pubsubMetricsLock.Lock()
+	defer pubsubMetricsLock.Unlock()
+	metrics, ok := pubsubMetricsLoaded[registry]
+	if !ok {
+		metrics = &pubsubMetrics{registry: registry}
+		metrics.messages, _ = m.NewCounter(prometheus.CounterOpts{
+			Name: "redis_pubsub_message_count",
+			Help: "redis pubsub messages received per channel pattern",
+		}, "name", "channel")
+		pubsubMetricsLoaded[registry] = metrics
+	}
+	pubsubMetricsCurrent.Store(metrics)
+	return metrics
+}
+
+// MetricsReceiverWrapper 按频道统计收到的消息数，PSUBSCRIBE收到的消息按订阅的模式统计，
+// 避免模式匹配到的具体频道过多
+func MetricsReceiverWrapper(name string) ReceiverWrapper {
+	return func(next Receiver, ctx context.Context) *pubsub.SubReply {
+		reply := next(ctx)
+		if reply == nil || reply.Type != pubsub.MessageReply {
+			return reply
+		}
+		if metrics := loadPubsubMetrics(); metrics != nil && metrics.messages != nil {
+			channel := reply.Pattern
+			if channel == "" {
+				channel = reply.Channel
+			}
+			metrics.messages.WithLabelValues(name, channel).Inc()
+		}
+		return reply
+	}
+}
@@ -0,0 +1,43 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"umbrella-go/umbrella-common/monitor"
+)
+
+func TestMetricsReceiverWrapper(t *testing.T) {
+	assert := assert.New(t)
+
+	old := monitor.Monitor
+	monitor.Monitor = monitor.New(monitor.Config{Namespace: "test", Subsystem: "pubsub"})
+	defer func() { monitor.Monitor = old }()
+
+	replies := []*SubReply{
+		{Type: SubscribeReply, Channel: "news"},
+		{Type: MessageReply, Channel: "news", Message: "a"},
+		{Type: MessageReply, Channel: "news", Message: "b"},
+		// PSUBSCRIBE收到的消息按模式统计
+		{Type: MessageReply, Channel: "user.1", Pattern: "user.*", Message: "c"},
+		{Type: MessageReply, Channel: "user.2", Pattern: "user.*", Message: "d"},
+		{Type: ErrorReply},
+		nil,
+	}
+	receive := MetricsReceiverWrapper("events").Wrap(func(ctx context.Context) *SubReply {
+		reply := replies[0]
+		replies = replies[1:]
+		return reply
+	})
+	for len(replies) > 0 {
+		receive(context.Background())
+	}
+
+	metrics := loadPubsubMetrics()
+	assert.Equal(float64(2), testutil.ToFloat64(metrics.messages.WithLabelValues("events", "news")))
+	assert.Equal(float64(2), testutil.ToFloat64(metrics.messages.WithLabelValues("events", "user.*")))
+	assert.Equal(float64(0), testutil.ToFloat64(metrics.messages.WithLabelValues("events", "user.1")))
+}
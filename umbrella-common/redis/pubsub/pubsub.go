@@ -3,6 +3,7 @@ package pubsub
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/fzzy/radix/extra/pubsub"
 
@@ -71,6 +72,11 @@ func UnwrapSubClient(c *SubClient) *pubsub.SubClient {
 	return c.subClient
 }
 
+// Close 关闭订阅使用的连接，阻塞在Receive上的调用会返回错误
+func (c *SubClient) Close() error {
+	return c.subClient.Client.Close()
+}
+
 func (c *SubClient) defaultCmd(ctx context.Context, cmd string, patterns []interface{}) *pubsub.SubReply {
 	cmd = strings.ToUpper(cmd)
 	switch cmd {
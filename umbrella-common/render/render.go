@@ -7,6 +7,7 @@ import (
 
 	"umbrella-go/umbrella-common/errors"
 	"umbrella-go/umbrella-common/lang"
+	"umbrella-go/umbrella-common/monitor"
 )
 
 type RenderFunc func(w http.ResponseWriter, r *http.Request, v interface{})
@@ -19,7 +20,7 @@ func MakeJSON(errorMsgGetter ErrorMsgGetter) RenderFunc {
 		languages := lang.FromOutgoingContext(r.Context())
 
 		if err, ok := v.(errors.Error); ok {
-			// TODO monitor RequestWithRespCode
+			monitor.SetRespCode(r.Context(), err.GetCode())
 			if err.GetMessage() == "" {
 				if msg := errorMsgGetter(err.GetCode(), languages); msg != "" {
 					err.SetMessage(msg)
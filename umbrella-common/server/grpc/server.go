@@ -0,0 +1,165 @@
+package grpcserver
+
+import (
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+
+	grpccaller "umbrella-go/umbrella-common/caller/grpc"
+	grpchealth "umbrella-go/umbrella-common/health/grpc"
+	"umbrella-go/umbrella-common/middleware/grpc"
+	"umbrella-go/umbrella-common/monitor"
+)
+
+type KeepaliveConfig struct {
+	Time                  time.Duration // 连接空闲多久后服务端发送ping，为0时使用grpc的默认值(2小时)
+	Timeout               time.Duration // 等待ping响应的时长，为0时使用grpc的默认值(20秒)
+	MaxConnectionIdle     time.Duration
+	MaxConnectionAge      time.Duration // 连接的最长存活时间，便于客户端重新做负载均衡
+	MaxConnectionAgeGrace time.Duration
+	MinTime               time.Duration // 允许客户端发送ping的最小间隔，为0时使用grpc的默认值(5分钟)
+	PermitWithoutStream   bool
+}
+
+type Config struct {
+	Listen         string
+	Reflection     bool // 是否注册reflection服务，便于grpcurl等工具调试
+	MaxRecvMsgSize int  // 为0时使用grpc的默认值(4MB)
+	MaxSendMsgSize int
+	Keepalive      KeepaliveConfig
+	// TLS 不为空时使用TLS，通常由tlsutil.Reloader.ServerConfig生成，
+	// 要求客户端证书时，证书的subject覆盖caller-name作为调用方名称
+	TLS *tls.Config
+
+	// ErrorMsgGetter 不为空时按调用方的语言翻译响应中的错误信息，panic恢复后的错误信息也使用它
+	ErrorMsgGetter grpcmiddleware.ErrorMsgGetter
+	AccessLog      *grpcmiddleware.AccessLogConfig // 为空时不记录访问日志
+
+	// 在默认拦截器之后执行的拦截器
+	UnaryInterceptors  []grpc.UnaryServerInterceptor
+	StreamInterceptors []grpc.StreamServerInterceptor
+	ServerOptions      []grpc.ServerOption
+}
+
+// RegisterFunc 将服务注册到grpc.Server上，如pb.RegisterXxxServer(s, impl)
+type RegisterFunc func(s *grpc.Server)
+
+var (
+	registersMu sync.Mutex
+	registers   []RegisterFunc
+)
+
+// Register 添加全局的服务注册函数，通常在服务实现所在包的init中调用，New时统一注册
+func Register(fn RegisterFunc) {
+	registersMu.Lock()
+	defer registersMu.Unlock()
+	registers = append(registers, fn)
+}
+
+// DefaultUnaryInterceptors 默认的拦截器链，从外到内依次为：
+// panic恢复 -> RequestID -> 提取调用方(metadata、客户端证书) -> 监控 -> 访问日志 -> 错误信息翻译
+// panic恢复在最外层，其余拦截器中的panic也不会导致进程退出；监控和访问日志遇到panic时按codes.Internal记录后继续panic，
+// 访问日志在错误翻译外层，才能取到响应中的业务错误码
+func DefaultUnaryInterceptors(config Config) []grpc.UnaryServerInterceptor {
+	interceptors := []grpc.UnaryServerInterceptor{
+		grpcmiddleware.UnaryServerRecovery(config.ErrorMsgGetter),
+		grpcmiddleware.UnaryServerRequestID(),
+		grpccaller.ExtractCallerNameUnary(),
+		grpccaller.ExtractCallerFromClientCertUnary(),
+		monitor.MonitorInceptorUnary(),
+	}
+	if config.AccessLog != nil {
+		interceptors = append(interceptors, grpcmiddleware.UnaryServerAccessLog(*config.AccessLog))
+	}
+	if config.ErrorMsgGetter != nil {
+		interceptors = append(interceptors, grpcmiddleware.MakeUnaryServerErrorTranslator(config.ErrorMsgGetter))
+	}
+	return interceptors
+}
+
+func DefaultStreamInterceptors(config Config) []grpc.StreamServerInterceptor {
+	interceptors := []grpc.StreamServerInterceptor{
+		grpcmiddleware.StreamServerRecovery(config.ErrorMsgGetter),
+		grpcmiddleware.StreamServerRequestID(),
+		grpccaller.ExtractCallerNameStream(),
+		grpccaller.ExtractCallerFromClientCertStream(),
+		monitor.MonitorInceptorStream(),
+	}
+	if config.AccessLog != nil {
+		interceptors = append(interceptors, grpcmiddleware.StreamServerAccessLog(*config.AccessLog))
+	}
+	if config.ErrorMsgGetter != nil {
+		interceptors = append(interceptors, grpcmiddleware.MakeStreamServerErrorTranslator(config.ErrorMsgGetter))
+	}
+	return interceptors
+}
+
+func serverOptions(config Config) []grpc.ServerOption {
+	unary := append(DefaultUnaryInterceptors(config), config.UnaryInterceptors...)
+	stream := append(DefaultStreamInterceptors(config), config.StreamInterceptors...)
+
+	ka := config.Keepalive
+	opts := []grpc.ServerOption{
+		grpcmiddleware.WithUnaryServerChain(unary...),
+		grpcmiddleware.WithStreamServerChain(stream...),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:                  ka.Time,
+			Timeout:               ka.Timeout,
+			MaxConnectionIdle:     ka.MaxConnectionIdle,
+			MaxConnectionAge:      ka.MaxConnectionAge,
+			MaxConnectionAgeGrace: ka.MaxConnectionAgeGrace,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             ka.MinTime,
+			PermitWithoutStream: ka.PermitWithoutStream,
+		}),
+	}
+	if config.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config.TLS)))
+	}
+	if config.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(config.MaxRecvMsgSize))
+	}
+	if config.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(config.MaxSendMsgSize))
+	}
+	return append(opts, config.ServerOptions...)
+}
+
+// Server 包含grpc.Server以及基于health子系统的grpc.health.v1.Health服务
+type Server struct {
+	*grpc.Server
+	Health *grpchealth.Server
+	config Config
+}
+
+// New 使用默认拦截器链创建grpc.Server，并注册health服务、通过Register添加的服务和fns
+func New(config Config, fns ...RegisterFunc) *Server {
+	s := &Server{
+		Server: grpc.NewServer(serverOptions(config)...),
+		Health: grpchealth.NewServer(nil),
+		config: config,
+	}
+	s.Health.Register(s.Server)
+
+	registersMu.Lock()
+	all := append(append([]RegisterFunc(nil), registers...), fns...)
+	registersMu.Unlock()
+	for _, fn := range all {
+		fn(s.Server)
+	}
+
+	if config.Reflection {
+		reflection.Register(s.Server)
+	}
+	return s
+}
+
+func (s *Server) Addr() string {
+	return s.config.Listen
+}
@@ -0,0 +1,194 @@
+package grpcserver
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+
+	"umbrella-go/umbrella-common/health"
+	"umbrella-go/umbrella-common/json"
+	"umbrella-go/umbrella-common/log"
+	"umbrella-go/umbrella-common/middleware/grpc"
+	"umbrella-go/umbrella-common/monitor"
+)
+
+func testErrorMsg(code int, languages []string) string {
+	if len(languages) > 0 && languages[0] == "zh-CN" {
+		return "服务内部错误"
+	}
+	return "internal error"
+}
+
+// panicService 复用health的消息类型，Panic方法直接panic
+var panicService = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Panic",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(healthpb.HealthCheckRequest)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Echo/Panic"}
+			return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				panic("boom")
+			})
+		},
+	}},
+}
+
+// interceptorName 拦截器都是闭包，名称中包含返回它的函数名，内联时前缀可能不同
+func interceptorName(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+func assertInterceptors(t *testing.T, expected []string, interceptors []interface{}) {
+	if !assert.Equal(t, len(expected), len(interceptors)) {
+		return
+	}
+	for i, name := range expected {
+		assert.Contains(t, interceptorName(interceptors[i]), name+".func", "interceptor %d", i)
+	}
+}
+
+func TestDefaultInterceptorsOrder(t *testing.T) {
+	config := Config{ErrorMsgGetter: testErrorMsg, AccessLog: &grpcmiddleware.AccessLogConfig{}}
+	var unary, stream []interface{}
+	for _, interceptor := range DefaultUnaryInterceptors(config) {
+		unary = append(unary, interceptor)
+	}
+	for _, interceptor := range DefaultStreamInterceptors(config) {
+		stream = append(stream, interceptor)
+	}
+
+	assertInterceptors(t, []string{
+		"UnaryServerRecovery",
+		"UnaryServerRequestID",
+		"ExtractCallerNameUnary",
+		"ExtractCallerFromClientCertUnary",
+		"MonitorInceptorUnary",
+		"UnaryServerAccessLog",
+		"MakeUnaryServerErrorTranslator",
+	}, unary)
+	assertInterceptors(t, []string{
+		"StreamServerRecovery",
+		"StreamServerRequestID",
+		"ExtractCallerNameStream",
+		"ExtractCallerFromClientCertStream",
+		"MonitorInceptorStream",
+		"StreamServerAccessLog",
+		"MakeStreamServerErrorTranslator",
+	}, stream)
+
+	// 没有ErrorMsgGetter时不翻译，也没有访问日志
+	assert.Equal(t, 5, len(DefaultUnaryInterceptors(Config{})))
+	assert.Equal(t, 5, len(DefaultStreamInterceptors(Config{})))
+}
+
+func TestServer(t *testing.T) {
+	assert := assert.New(t)
+
+	old := monitor.Monitor
+	monitor.Monitor = monitor.New(monitor.Config{Namespace: "test", Subsystem: "grpcserver"})
+	defer func() { monitor.Monitor = old }()
+
+	f, err := ioutil.TempFile("", "access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	logger, err := log.New(log.Config{Output: f.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{
+		Listen:         lis.Addr().String(),
+		Reflection:     true,
+		ErrorMsgGetter: testErrorMsg,
+		AccessLog:      &grpcmiddleware.AccessLogConfig{Logger: logger},
+	}, func(s *grpc.Server) {
+		s.RegisterService(&panicService, struct{}{})
+	})
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := grpc.Dial(s.Addr(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+
+	// panic被最外层的recovery转换为codes.Internal，监控和访问日志记录为Internal
+	zhCtx := metadata.NewOutgoingContext(ctx, metadata.Pairs("language", "zh-CN"))
+	err = conn.Invoke(zhCtx, "/test.Echo/Panic", &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{})
+	assert.Equal(codes.Internal, status.Code(err))
+	assert.Equal("服务内部错误", status.Convert(err).Message())
+
+	counter, err := monitor.Monitor.Counter("unknown", "Panic", "13")
+	assert.Nil(err)
+	assert.Equal(float64(1), testutil.ToFloat64(counter.(prometheus.Counter)))
+
+	data, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry map[string]interface{}
+	assert.Nil(json.Unmarshal(bytes.TrimSpace(data), &entry))
+	assert.Equal("/test.Echo/Panic", entry["method"])
+	assert.Equal("Internal", entry["code"])
+
+	// health服务
+	client := healthpb.NewHealthClient(conn)
+	health.SetReady(true)
+	defer health.SetReady(false)
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(err)
+	assert.Equal(healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	// reflection服务
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{ListServices: "*"},
+	}))
+	reflectionResp, err := stream.Recv()
+	if assert.Nil(err) {
+		var services []string
+		for _, service := range reflectionResp.GetListServicesResponse().GetService() {
+			services = append(services, service.Name)
+		}
+		assert.Contains(services, "grpc.health.v1.Health")
+		assert.Contains(services, "grpc.reflection.v1alpha.ServerReflection")
+		assert.Contains(services, "test.Echo")
+	}
+	// 等待服务端的handler和拦截器返回，避免与恢复monitor.Monitor冲突
+	stream.CloseSend()
+	_, err = stream.Recv()
+	assert.Equal(io.EOF, err)
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// Mux 在同一个端口上同时提供HTTP/1.1、h2c(明文HTTP/2)或TLS上的HTTP/2和gRPC，
+// HTTP/2且content-type为application/grpc的请求交给gRPC服务，其余请求交给httpHandler
+// gRPC通过grpc.Server.ServeHTTP处理，grpc.Server上的keepalive等传输层配置不生效
+type Mux struct {
+	server *http.Server
+	grpc   *grpc.Server
+	tls    bool
+
+	inFlight int64
+}
+
+// NewMux httpHandler通常是经过DefaultServerChain包装的chi路由，
+// gRPC请求不经过httpHandler上的中间件(如压缩、超时)
+// tlsConfig不为空时使用TLS，通过ALPN协商HTTP/2，此时grpc.Server上的Creds不生效
+func NewMux(addr string, httpHandler http.Handler, grpcServer *grpc.Server, tlsConfig *tls.Config) *Mux {
+	m := &Mux{grpc: grpcServer, tls: tlsConfig != nil}
+
+	h2s := &http2.Server{}
+	// TLSConfig需要在ConfigureServer之前设置，ConfigureServer会在NextProtos中添加h2，
+	// TLSConfig为空时ConfigureServer会创建一个，因此是否使用TLS记录在m.tls中
+	m.server = &http.Server{Addr: addr, TLSConfig: tlsConfig}
+	// 使Shutdown时通过GOAWAY通知h2c连接，h2c的连接被hijack之后不再由http.Server管理
+	if err := http2.ConfigureServer(m.server, h2s); err != nil {
+		panic(err)
+	}
+	m.server.Handler = h2c.NewHandler(m.dispatch(httpHandler), h2s)
+	return m
+}
+
+func isGRPC(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+func (m *Mux) dispatch(httpHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&m.inFlight, 1)
+		defer atomic.AddInt64(&m.inFlight, -1)
+
+		if isGRPC(r) {
+			m.grpc.ServeHTTP(w, r)
+			return
+		}
+		httpHandler.ServeHTTP(w, r)
+	})
+}
+
+func (m *Mux) Addr() string {
+	return m.server.Addr
+}
+
+func (m *Mux) ListenAndServe() error {
+	if m.tls {
+		return m.server.ListenAndServeTLS("", "")
+	}
+	return m.server.ListenAndServe()
+}
+
+// Serve 调用Shutdown之后返回http.ErrServerClosed
+func (m *Mux) Serve(ln net.Listener) error {
+	if m.tls {
+		return m.server.ServeTLS(ln, "", "")
+	}
+	return m.server.Serve(ln)
+}
+
+// Shutdown 停止接收新连接，通知h2c连接不再发起新的请求，并等待进行中的HTTP和gRPC请求结束，
+// ctx超时后强制关闭所有连接
+func (m *Mux) Shutdown(ctx context.Context) error {
+	err := m.server.Shutdown(ctx)
+	if err == nil {
+		err = m.waitInFlight(ctx)
+	}
+	if err != nil {
+		m.server.Close()
+	}
+	m.grpc.Stop()
+	return err
+}
+
+// waitInFlight h2c连接上的请求不受http.Server.Shutdown跟踪，需要单独等待
+func (m *Mux) waitInFlight(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&m.inFlight) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
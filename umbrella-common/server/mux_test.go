@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"umbrella-go/umbrella-common/health"
+	grpchealth "umbrella-go/umbrella-common/health/grpc"
+)
+
+// newTestTLS 生成127.0.0.1的自签名证书，返回服务端配置以及信任该证书的CertPool
+func newTestTLS(t *testing.T) (*tls.Config, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mux"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}}}, pool
+}
+
+// startMux 在随机端口上启动Mux，HTTP请求返回请求使用的协议，gRPC提供h的health服务
+func startMux(t *testing.T, h *health.Health, tlsConfig *tls.Config) (*Mux, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	grpchealth.NewServer(h).Register(gs)
+	httpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+	m := NewMux(ln.Addr().String(), httpHandler, gs, tlsConfig)
+	go m.Serve(ln)
+	return m, ln.Addr().String()
+}
+
+func httpGet(t *testing.T, client *http.Client, url string) string {
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func healthCheck(conn *grpc.ClientConn) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Status, nil
+}
+
+func TestMuxH2C(t *testing.T) {
+	assert := assert.New(t)
+
+	h := health.New()
+	h.SetReady(true)
+	m, addr := startMux(t, h, nil)
+	defer m.Shutdown(context.Background())
+
+	assert.Equal("HTTP/1.1", httpGet(t, http.DefaultClient, "http://"+addr+"/"))
+
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	st, err := healthCheck(conn)
+	assert.Nil(err)
+	assert.Equal(healthpb.HealthCheckResponse_SERVING, st)
+}
+
+func TestMuxTLS(t *testing.T) {
+	assert := assert.New(t)
+
+	serverTLS, pool := newTestTLS(t)
+	h := health.New()
+	h.SetReady(true)
+	m, addr := startMux(t, h, serverTLS)
+	defer m.Shutdown(context.Background())
+
+	// 自定义TLSClientConfig的Transport不会协商HTTP/2
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	assert.Equal("HTTP/1.1", httpGet(t, client, "https://"+addr+"/"))
+
+	// gRPC客户端通过ALPN协商h2
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	st, err := healthCheck(conn)
+	assert.Nil(err)
+	assert.Equal(healthpb.HealthCheckResponse_SERVING, st)
+}
+
+func TestMuxShutdownWaitsForH2C(t *testing.T) {
+	assert := assert.New(t)
+
+	// readiness检查阻塞到release关闭，模拟进行中的gRPC请求
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := health.New()
+	h.SetReady(true)
+	h.Register("slow", health.CheckerFunc(func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}), health.WithTimeout(5*time.Second))
+	m, addr := startMux(t, h, nil)
+
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	type result struct {
+		status healthpb.HealthCheckResponse_ServingStatus
+		err    error
+	}
+	checked := make(chan result, 1)
+	go func() {
+		st, err := healthCheck(conn)
+		checked <- result{st, err}
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- m.Shutdown(context.Background())
+	}()
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned before the in-flight h2c request finished")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-shutdown:
+		assert.Nil(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not return")
+	}
+	r := <-checked
+	assert.Nil(r.err)
+	assert.Equal(healthpb.HealthCheckResponse_SERVING, r.status)
+
+	// 超时后强制关闭
+	started = make(chan struct{})
+	release = make(chan struct{})
+	defer close(release)
+	m, addr = startMux(t, h, nil)
+	conn2, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+	go healthCheck(conn2)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, m.Shutdown(ctx))
+}
@@ -0,0 +1,265 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	ClientAuthNone    = "none"    // 不要求客户端证书
+	ClientAuthRequest = "request" // 客户端提供证书时校验，不提供也允许连接
+	ClientAuthRequire = "require" // 必须提供CA签发的客户端证书(mTLS)
+)
+
+const defaultReloadInterval = time.Minute
+
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// CAFile 服务端用于校验客户端证书，客户端用于校验服务端证书，为空时客户端使用系统根证书
+	CAFile     string
+	ClientAuth string // 服务端校验客户端证书的方式，默认none
+	ServerName string // 客户端校验服务端证书时使用的名称，为空时使用连接地址中的主机名
+	// ReloadInterval 检查证书文件是否更新的间隔，为0时使用1分钟，小于0时不重新加载
+	ReloadInterval time.Duration
+	// OnError 重新加载失败时调用，为空时忽略
+	OnError func(err error)
+}
+
+// Reloader 定期检查证书、私钥和CA文件的修改时间，变化时重新加载，
+// 加载失败时继续使用之前的证书，证书轮换后新建立的连接使用新证书
+type Reloader struct {
+	config Config
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime map[string]time.Time
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func NewReloader(config Config) (*Reloader, error) {
+	r := &Reloader{
+		config:  config,
+		modTime: make(map[string]time.Time),
+		stop:    make(chan struct{}),
+	}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	interval := config.ReloadInterval
+	if interval == 0 {
+		interval = defaultReloadInterval
+	}
+	if interval > 0 {
+		go r.watch(interval)
+	}
+	return r, nil
+}
+
+func (r *Reloader) files() []string {
+	var files []string
+	for _, f := range []string{r.config.CertFile, r.config.KeyFile, r.config.CAFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// changed 返回修改时间有变化的文件，首次加载时所有文件都视为有变化
+func (r *Reloader) changed() (map[string]time.Time, bool, error) {
+	modTime := make(map[string]time.Time)
+	changed := false
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, false, err
+		}
+		modTime[f] = info.ModTime()
+		if !info.ModTime().Equal(r.modTime[f]) {
+			changed = true
+		}
+	}
+	return modTime, changed, nil
+}
+
+func (r *Reloader) reload() (bool, error) {
+	modTime, changed, err := r.changed()
+	if err != nil || !changed {
+		return false, err
+	}
+
+	var cert *tls.Certificate
+	if r.config.CertFile != "" || r.config.KeyFile != "" {
+		c, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+		if err != nil {
+			return false, err
+		}
+		cert = &c
+	}
+
+	var pool *x509.CertPool
+	if r.config.CAFile != "" {
+		data, err := ioutil.ReadFile(r.config.CAFile)
+		if err != nil {
+			return false, err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return false, fmt.Errorf("no certificates found in %s", r.config.CAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.pool, r.modTime = cert, pool, modTime
+	r.mu.Unlock()
+	return true, nil
+}
+
+func (r *Reloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := r.reload(); err != nil && r.config.OnError != nil {
+				r.config.OnError(err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Close 停止检查文件更新
+func (r *Reloader) Close() error {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	return nil
+}
+
+func (r *Reloader) certificate() (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.cert == nil {
+		return nil, errors.New("no certificate configured")
+	}
+	return r.cert, nil
+}
+
+func (r *Reloader) certPool() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pool
+}
+
+func clientAuthType(clientAuth string) (tls.ClientAuthType, error) {
+	switch clientAuth {
+	case ClientAuthNone, "":
+		return tls.NoClientCert, nil
+	case ClientAuthRequest:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("unknown client auth: %q", clientAuth)
+	}
+}
+
+// ServerConfig 服务端使用的tls.Config，证书和校验客户端证书的CA都支持热更新
+func (r *Reloader) ServerConfig() (*tls.Config, error) {
+	clientAuth, err := clientAuthType(r.config.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	if clientAuth != tls.NoClientCert && r.config.CAFile == "" {
+		return nil, errors.New("client auth requires CAFile")
+	}
+
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: clientAuth,
+		// GetConfigForClient返回的配置不包含http.Server和grpc在各自副本中添加的ALPN协议，需要在这里声明
+		NextProtos: []string{"h2", "http/1.1"},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.certificate()
+		},
+	}
+	// 每次握手使用最新的CA，CA轮换时不需要重启
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := base.Clone()
+		c.GetConfigForClient = nil
+		c.ClientCAs = r.certPool()
+		return c, nil
+	}
+	return base, nil
+}
+
+// ClientConfig 客户端使用的tls.Config，配置了证书时用于mTLS，
+// 配置了CAFile时使用最新的CA校验服务端证书
+func (r *Reloader) ClientConfig() *tls.Config {
+	c := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: r.config.ServerName,
+	}
+	if r.config.CertFile != "" {
+		c.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.certificate()
+		}
+	}
+	if r.config.CAFile != "" {
+		// RootCAs在tls.Config中无法热更新，关闭默认校验，改为在VerifyConnection中使用最新的CA校验
+		c.InsecureSkipVerify = true
+		c.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("no server certificate")
+			}
+			// 通过IP连接时不发送SNI，cs.ServerName为空，需要配置ServerName
+			name := cs.ServerName
+			if name == "" {
+				name = r.config.ServerName
+			}
+			if name == "" {
+				return errors.New("server name is required to verify server certificate")
+			}
+			opts := x509.VerifyOptions{
+				DNSName:       name,
+				Roots:         r.certPool(),
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	}
+	return c
+}
+
+// SubjectName 返回经过校验的客户端证书的CommonName，没有时返回第一个DNS SAN，
+// 连接没有经过校验的证书时返回空字符串
+func SubjectName(state *tls.ConnectionState) string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	cert := state.VerifiedChains[0][0]
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert, serial int64) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	if err := ioutil.WriteFile(name, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handshake 返回服务端看到的客户端证书subject和客户端看到的服务端证书CN
+func handshake(t *testing.T, server, client *tls.Config) (string, string, error) {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	subject := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			subject <- ""
+			return
+		}
+		defer conn.Close()
+		tlsConn := conn.(*tls.Conn)
+		tlsConn.Handshake()
+		state := tlsConn.ConnectionState()
+		subject <- SubjectName(&state)
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), client)
+	if err != nil {
+		<-subject
+		return "", "", err
+	}
+	defer conn.Close()
+	state := conn.ConnectionState()
+	if len(client.NextProtos) > 0 && state.NegotiatedProtocol != client.NextProtos[0] {
+		t.Errorf("negotiated protocol %q", state.NegotiatedProtocol)
+	}
+	serverCN := state.PeerCertificates[0].Subject.CommonName
+	return <-subject, serverCN, nil
+}
+
+func TestReloaderMutualTLS(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "tlsutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "test-ca", nil, 1)
+	caFile, _ := ca.write(t, dir, "ca")
+	serverCert, serverKey := newTestCert(t, "server-v1", ca, 2).write(t, dir, "server")
+	clientCert, clientKey := newTestCert(t, "umbrella-worker", ca, 3).write(t, dir, "client")
+
+	server, err := NewReloader(Config{CertFile: serverCert, KeyFile: serverKey, CAFile: caFile, ClientAuth: ClientAuthRequire, ReloadInterval: -1})
+	assert.Nil(err)
+	serverConfig, err := server.ServerConfig()
+	assert.Nil(err)
+
+	client, err := NewReloader(Config{CertFile: clientCert, KeyFile: clientKey, CAFile: caFile, ServerName: "localhost", ReloadInterval: -1})
+	assert.Nil(err)
+
+	clientConfig := client.ClientConfig()
+	clientConfig.NextProtos = []string{"h2"}
+	subject, serverCN, err := handshake(t, serverConfig, clientConfig)
+	assert.Nil(err)
+	assert.Equal("umbrella-worker", subject)
+	assert.Equal("server-v1", serverCN)
+
+	// 没有客户端证书时拒绝连接
+	anonymous, err := NewReloader(Config{CAFile: caFile, ServerName: "localhost", ReloadInterval: -1})
+	assert.Nil(err)
+	// TLS1.3中客户端在服务端校验证书之前就完成了握手，Dial不一定返回错误
+	subject, _, _ = handshake(t, serverConfig, anonymous.ClientConfig())
+	assert.Equal("", subject)
+
+	// 证书轮换后新建立的连接使用新证书
+	newTestCert(t, "server-v2", ca, 4).write(t, dir, "server")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(serverCert, future, future)
+	reloaded, err := server.reload()
+	assert.Nil(err)
+	assert.True(reloaded)
+
+	_, serverCN, err = handshake(t, serverConfig, client.ClientConfig())
+	assert.Nil(err)
+	assert.Equal("server-v2", serverCN)
+
+	// 文件没有变化时不重新加载
+	reloaded, err = server.reload()
+	assert.Nil(err)
+	assert.False(reloaded)
+}
+
+func TestServerConfigRequiresCA(t *testing.T) {
+	r := &Reloader{config: Config{ClientAuth: ClientAuthRequire}}
+	_, err := r.ServerConfig()
+	assert.NotNil(t, err)
+
+	r = &Reloader{config: Config{ClientAuth: "always"}}
+	_, err = r.ServerConfig()
+	assert.NotNil(t, err)
+}